@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// --- Админка ---
+
+// Ручки для модерации, доступные только с заголовком X-Admin-Token, равным config.AdminToken
+// (без токена ручки не регистрируются):
+//
+//	GET  /admin           - игроки всех комнат: ID, комната, ник, счёт, адрес, время подключения
+//	POST /admin/kick?id=X - отключить игрока X
+//
+// Кикнутый игрок проходит обычную очистку в reader, но его танк не ждёт переподключения:
+// токен сессии сбрасывается, а танк убирается через config.DisconnectLinger.
+
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminPlayer - игрок в списке админки
+type AdminPlayer struct {
+	ID           string `json:"id"`
+	Room         string `json:"room"`
+	Nickname     string `json:"nickname"`
+	Score        int    `json:"score"`
+	Lives        int    `json:"lives"`
+	IP           string `json:"ip,omitempty"`          // Адрес клиента (у ботов пусто)
+	ConnectedAt  int64  `json:"connectedAt,omitempty"` // Начало текущего соединения, мс Unix
+	Bot          bool   `json:"bot"`
+	Disconnected bool   `json:"disconnected"`
+}
+
+// clientIP возвращает адрес клиента. За доверенным ретранслятором (см. relay.go) это адрес
+// из X-Forwarded-For, иначе - адрес соединения: заголовки от кого попало не проверить.
+func clientIP(r *http.Request) string {
+	if r.Header.Get(RelayHeader) != "" && fromTrustedRelay(r) {
+		if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
+			return ip
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// requireAdmin пропускает к обработчику только запросы с верным админским токеном
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+			slog.Warn("Отказ в доступе к админке", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			http.Error(w, "неверный админский токен", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminPlayers - GET /admin: игроки всех комнат
+func handleAdminPlayers(w http.ResponseWriter, r *http.Request) {
+	list := []AdminPlayer{}
+	for _, room := range rooms.all() {
+		game := room.Game
+		game.mutex.RLock()
+		for _, p := range game.Players {
+			entry := AdminPlayer{
+				ID:           p.ID,
+				Room:         room.ID,
+				Nickname:     p.Nickname,
+				Score:        p.Score,
+				Lives:        p.Lives,
+				IP:           p.IP,
+				Bot:          p.Bot,
+				Disconnected: p.Disconnected,
+			}
+			if !p.ConnectedAt.IsZero() {
+				entry.ConnectedAt = p.ConnectedAt.UnixMilli()
+			}
+			list = append(list, entry)
+		}
+		game.mutex.RUnlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleAdminKick - POST /admin/kick?id=X: отключает игрока X
+func handleAdminKick(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	for _, room := range rooms.all() {
+		game := room.Game
+		game.mutex.Lock()
+		p, ok := game.Players[id]
+		if ok {
+			game.kick(p)
+		}
+		game.mutex.Unlock()
+		if ok {
+			slog.Info("Игрок кикнут админом", "player_id", id, "room", room.ID, "remote_addr", r.RemoteAddr)
+			fmt.Fprintf(w, "игрок %s отключён\n", id)
+			return
+		}
+	}
+	http.Error(w, "игрок не найден", http.StatusNotFound)
+}
+
+// kick отключает игрока без права переподключения. Вызывается под game.mutex.
+func (game *GameState) kick(p *Player) {
+	p.SessionToken = ""
+	if p.Conn == nil || p.Disconnected {
+		// Соединения нет (бот или танк, ждущий переподключения) - убираем сразу
+		game.removePlayer(p.ID)
+		game.emit(GameEvent{Kind: EventPlayerRemoved, PlayerID: p.ID})
+		return
+	}
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "kicked")
+	p.Conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(connRejectWriteTimeout))
+	disconnectPlayer(p, DisconnectKicked) // Reader выполнит очистку
+}
@@ -0,0 +1,69 @@
+package main
+
+import "math"
+
+// --- Прицеливание ---
+
+// Клиенты с мышью присылают точку прицела (AimX/AimY), клиенты с геймпадом - вектор стика
+// (StickX/StickY), клавиатурные клиенты - направление в команде "shoot". Всё это задаёт лишь
+// желаемый угол башни (DesiredAimAngle); сама башня (AimAngle) каждый тик поворачивается к нему
+// кратчайшим путём со скоростью не больше TurretTurnRate. Если стик в мёртвой зоне,
+// используется угол прицела из двоичного ввода или точка прицела (если она есть). Угол по точке
+// прицела считается только здесь, в игровом цикле, от позиции танка в этом тике: reader лишь
+// сохраняет ввод.
+//
+// Выстрел уходит по текущему углу башни, поэтому ждёт, пока башня не довернёт до желаемого
+// угла с точностью TurretFireTolerance (перезарядка при этом не начинается).
+const (
+	TurretTurnRate      = math.Pi * 2 // Скорость поворота башни, рад/с
+	TurretFireTolerance = 0.1         // Насколько башня может не довернуть до желаемого угла при выстреле, рад
+	StickDeadzone       = 0.15        // Отклонения стика меньше этого значения игнорируются
+)
+
+// normalizeAngle приводит угол к диапазону [-π, π)
+func normalizeAngle(a float64) float64 {
+	a = math.Mod(a+math.Pi, 2*math.Pi)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a - math.Pi
+}
+
+// turnToward поворачивает угол from к углу to кратчайшим путём не более чем на maxStep радиан
+func turnToward(from, to, maxStep float64) float64 {
+	diff := normalizeAngle(to - from)
+	if math.Abs(diff) <= maxStep {
+		return normalizeAngle(to)
+	}
+	return normalizeAngle(from + math.Copysign(maxStep, diff))
+}
+
+// usesStick сообщает, управляет ли ввод башней через стик
+func (in PlayerInput) usesStick() bool {
+	return math.Hypot(in.StickX, in.StickY) > StickDeadzone
+}
+
+// updateAim обновляет желаемый угол башни по вводу игрока и поворачивает к нему башню за шаг dt.
+// Вызывается под game.mutex.
+func updateAim(player *Player, dt float64) {
+	switch {
+	case player.Input.usesStick():
+		player.DesiredAimAngle = math.Atan2(player.Input.StickY, player.Input.StickX)
+	case player.Input.AimByAngle:
+		player.DesiredAimAngle = player.Input.AimAngle
+	case player.Input.AimX != 0 || player.Input.AimY != 0:
+		player.DesiredAimAngle = math.Atan2(player.Input.AimY-player.Y, player.Input.AimX-player.X)
+	}
+	player.AimAngle = turnToward(player.AimAngle, player.DesiredAimAngle, TurretTurnRate*dt)
+}
+
+// turretAligned сообщает, довернула ли башня до желаемого угла настолько, чтобы стрелять
+func turretAligned(player *Player) bool {
+	return math.Abs(normalizeAngle(player.DesiredAimAngle-player.AimAngle)) <= TurretFireTolerance
+}
+
+// setAim сразу ставит башню под углом angle (появление, сценарий) - без поворота
+func setAim(player *Player, angle float64) {
+	player.AimAngle = angle
+	player.DesiredAimAngle = angle
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// Угол из двоичного ввода не зависит от того, куда танк уехал между приёмом ввода и тиком
+func TestBinaryAimKeepsAngleAfterMove(t *testing.T) {
+	game := newGameState(defaultMap())
+	p := &Player{X: 100, Y: 100}
+	if err := game.applyBinaryInput(p, BinaryInput{HasAim: true, AimAngle: math.Pi / 2}); err != nil {
+		t.Fatal(err)
+	}
+	p.X, p.Y = 600, 400
+	updateAim(p, 10)
+	if math.Abs(p.DesiredAimAngle-math.Pi/2) > 1e-9 || math.Abs(p.AimAngle-math.Pi/2) > 1e-9 {
+		t.Fatalf("желаемый угол %v, угол башни %v, ожидалось π/2", p.DesiredAimAngle, p.AimAngle)
+	}
+}
+
+func TestAimPointUsesCurrentPosition(t *testing.T) {
+	p := &Player{X: 100, Y: 100, Input: PlayerInput{AimX: 200, AimY: 100}}
+	updateAim(p, 10)
+	if math.Abs(p.DesiredAimAngle) > 1e-9 {
+		t.Fatalf("желаемый угол %v, ожидался 0", p.DesiredAimAngle)
+	}
+	p.X, p.Y = 200, 0 // Точка прицела теперь прямо под танком
+	updateAim(p, 10)
+	if math.Abs(p.DesiredAimAngle-math.Pi/2) > 1e-9 {
+		t.Fatalf("желаемый угол %v, ожидалось π/2", p.DesiredAimAngle)
+	}
+}
+
+func TestTurretTurnRateLimited(t *testing.T) {
+	p := &Player{Input: PlayerInput{StickX: -1}} // Назад: π от текущего угла 0
+	updateAim(p, 0.1)
+	if got, want := math.Abs(p.AimAngle), TurretTurnRate*0.1; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("башня повернулась на %v за 0.1 с, ожидалось %v", got, want)
+	}
+	if turretAligned(p) {
+		t.Fatal("недовёрнутая башня считается готовой к выстрелу")
+	}
+}
+
+func TestStickAimOverridesPoint(t *testing.T) {
+	p := &Player{X: 100, Y: 100, Input: PlayerInput{AimX: 200, AimY: 100, StickX: 0, StickY: -1}}
+	updateAim(p, 10)
+	if math.Abs(p.DesiredAimAngle+math.Pi/2) > 1e-9 {
+		t.Fatalf("желаемый угол %v, ожидалось -π/2 по стику", p.DesiredAimAngle)
+	}
+}
+
+func TestStickDeadzoneFallsBackToPoint(t *testing.T) {
+	p := &Player{X: 100, Y: 100, Input: PlayerInput{AimX: 100, AimY: 200, StickX: StickDeadzone / 2}}
+	updateAim(p, 10)
+	if math.Abs(p.DesiredAimAngle-math.Pi/2) > 1e-9 {
+		t.Fatalf("желаемый угол %v, ожидалось π/2 по точке прицела", p.DesiredAimAngle)
+	}
+
+	// Без точки прицела и со стиком в мёртвой зоне башня держит прежний угол
+	p = &Player{DesiredAimAngle: 1, AimAngle: 1, Input: PlayerInput{StickX: StickDeadzone / 2}}
+	updateAim(p, 10)
+	if p.DesiredAimAngle != 1 || p.AimAngle != 1 {
+		t.Fatalf("башня повернулась к %v без ввода", p.DesiredAimAngle)
+	}
+}
+
+func TestTurnTowardShortestPath(t *testing.T) {
+	// От 170° к -170° короче через 180°, а не назад через 0
+	from, to := 170*math.Pi/180, -170*math.Pi/180
+	got := turnToward(from, to, 5*math.Pi/180)
+	if want := normalizeAngle(175 * math.Pi / 180); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("поворот к %v, ожидалось %v", got, want)
+	}
+}
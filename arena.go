@@ -0,0 +1,65 @@
+package main
+
+import "math"
+
+// --- Форма арены ---
+
+// Арена бывает прямоугольной (по умолчанию) или круглой: "shape": "circle" в файле карты.
+// Круглая арена вписана в прямоугольник Width x Height карты - центр в его середине, радиус -
+// половина меньшей стороны. Танки удерживаются внутри формы, снаряды за её краем исчезают.
+// Закольцованный край (EdgeWrap) и обрывы бывают только у прямоугольной арены.
+
+// Формы арены
+const (
+	ShapeRect   = "rect"
+	ShapeCircle = "circle"
+)
+
+// Arena - граница арены
+type Arena interface {
+	// Contains сообщает, находится ли точка внутри арены
+	Contains(x, y float64) bool
+	// Clamp возвращает ближайшую к (x, y) точку, в которой круг радиуса r целиком помещается на арене
+	Clamp(x, y, r float64) (float64, float64)
+}
+
+// RectArena - прямоугольная арена от (0, 0) до (Width, Height)
+type RectArena struct {
+	Width, Height float64
+}
+
+func (a RectArena) Contains(x, y float64) bool {
+	return x >= 0 && x <= a.Width && y >= 0 && y <= a.Height
+}
+
+func (a RectArena) Clamp(x, y, r float64) (float64, float64) {
+	return math.Max(r, math.Min(a.Width-r, x)), math.Max(r, math.Min(a.Height-r, y))
+}
+
+// CircleArena - круглая арена с центром (CX, CY) и радиусом R
+type CircleArena struct {
+	CX, CY, R float64
+}
+
+func (a CircleArena) Contains(x, y float64) bool {
+	return math.Hypot(x-a.CX, y-a.CY) <= a.R
+}
+
+func (a CircleArena) Clamp(x, y, r float64) (float64, float64) {
+	dx, dy := x-a.CX, y-a.CY
+	dist := math.Hypot(dx, dy)
+	limit := math.Max(0, a.R-r)
+	if dist <= limit {
+		return x, y
+	}
+	return a.CX + dx/dist*limit, a.CY + dy/dist*limit
+}
+
+// arena возвращает границу арены карты
+func (m *MapDef) arena() Arena {
+	w, h := float64(m.Width), float64(m.Height)
+	if m.Shape == ShapeCircle {
+		return CircleArena{CX: w / 2, CY: h / 2, R: math.Min(w, h) / 2}
+	}
+	return RectArena{Width: w, Height: h}
+}
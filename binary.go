@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// --- Двоичный протокол ---
+
+// Компактный формат ввода для клиентов с ограниченным каналом (WebSocket BinaryMessage, 4 байта):
+//
+//	байт 0:    тип сообщения (BinaryInputKind)
+//	байт 1:    биты: 0 - вверх, 1 - вниз, 2 - влево, 3 - вправо, 4 - выстрел, 5 - угол прицела задан
+//	байты 2-3: угол прицела, uint16 big-endian: 0 соответствует -π, 65535 - почти +π
+const BinaryInputKind = 0x01
+
+const (
+	binaryUp = 1 << iota
+	binaryDown
+	binaryLeft
+	binaryRight
+	binaryShoot
+	binaryHasAim
+)
+
+const binaryInputSize = 4
+
+var errBadBinaryInput = errors.New("некорректное двоичное сообщение ввода")
+
+// BinaryInput - расшифрованное двоичное сообщение ввода
+type BinaryInput struct {
+	Up, Down, Left, Right bool
+	Shoot                 bool
+	HasAim                bool
+	AimAngle              float64 // Радианы в диапазоне [-π, π)
+}
+
+// decodeBinaryInput разбирает двоичное сообщение ввода
+func decodeBinaryInput(data []byte) (BinaryInput, error) {
+	if len(data) != binaryInputSize || data[0] != BinaryInputKind {
+		return BinaryInput{}, errBadBinaryInput
+	}
+	flags := data[1]
+	quantized := binary.BigEndian.Uint16(data[2:4])
+	return BinaryInput{
+		Up:       flags&binaryUp != 0,
+		Down:     flags&binaryDown != 0,
+		Left:     flags&binaryLeft != 0,
+		Right:    flags&binaryRight != 0,
+		Shoot:    flags&binaryShoot != 0,
+		HasAim:   flags&binaryHasAim != 0,
+		AimAngle: float64(quantized)/65536*2*math.Pi - math.Pi,
+	}, nil
+}
+
+// applyBinaryInput применяет двоичный ввод к игроку. Ввод проходит те же проверки, что и JSON
+// (validateInput, clampInput); некорректный отбрасывается целиком. Вызывается под game.mutex.
+func (game *GameState) applyBinaryInput(p *Player, in BinaryInput) error {
+	input := p.Input
+	input.Up, input.Down, input.Left, input.Right = in.Up, in.Down, in.Left, in.Right
+	if in.HasAim {
+		// Сохраняем сам угол: точка, посчитанная от позиции танка сейчас, к выстрелу устареет
+		input.AimAngle, input.AimByAngle = in.AimAngle, true
+	}
+	if err := validateInput(input); err != nil {
+		return err
+	}
+	game.clampInput(&input)
+	p.Input = input
+	if in.Shoot {
+		p.WantsToShoot = true
+		p.ShotSeq = 0    // В двоичном формате нет номера выстрела
+		p.ShotCharge = 0 // и заряда
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDecodeBinaryInput(t *testing.T) {
+	in, err := decodeBinaryInput([]byte{BinaryInputKind, binaryUp | binaryRight | binaryShoot | binaryHasAim, 0xC0, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !in.Up || in.Down || in.Left || !in.Right || !in.Shoot || !in.HasAim {
+		t.Fatalf("флаги разобраны неверно: %+v", in)
+	}
+	if math.Abs(in.AimAngle-math.Pi/2) > 1e-9 { // 0xC000 - три четверти круга от -π
+		t.Fatalf("угол %v, ожидалось π/2", in.AimAngle)
+	}
+
+	for _, angle := range []uint16{0, 1, 0x8000, 0xFFFF} {
+		in, _ := decodeBinaryInput([]byte{BinaryInputKind, 0, byte(angle >> 8), byte(angle)})
+		if in.AimAngle < -math.Pi || in.AimAngle >= math.Pi {
+			t.Errorf("угол %#x раскодирован в %v - вне [-π, π)", angle, in.AimAngle)
+		}
+	}
+}
+
+func TestDecodeBinaryInputRejectsMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		{BinaryInputKind, 0, 0},
+		{BinaryInputKind, 0, 0, 0, 0},
+		{BinaryStateKind, 0, 0, 0},
+	} {
+		if _, err := decodeBinaryInput(data); err == nil {
+			t.Errorf("сообщение %v принято", data)
+		}
+	}
+}
+
+// Двоичный ввод по WebSocket доходит до игрока так же, как JSON
+func TestBinaryInputOverWebSocket(t *testing.T) {
+	withConfig(t, nil)
+	url := startTestServer(t)
+	conn := dialTest(t, url)
+	id, _ := assignedSession(t, conn)
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte{BinaryInputKind, binaryLeft | binaryHasAim, 0x80, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	game := rooms.defaultGame()
+	waitFor(t, game, "применение двоичного ввода", func() bool { return game.Players[id].Input.Left })
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	if in := game.Players[id].Input; !in.AimByAngle || in.AimAngle != 0 || in.Up {
+		t.Fatalf("ввод применён неверно: %+v", in)
+	}
+}
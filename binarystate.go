@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// --- Двоичное состояние игры ---
+
+// Клиент, подключившийся с /ws?encoding=binary, получает состояние игры не JSON-сообщением
+// gameState, а WebSocket BinaryMessage с полным состоянием в компактном формате (рассылка
+// изменений и разбиение на части для него не используются). Остальные сообщения остаются JSON.
+// По умолчанию (encoding=json) всё в JSON - так удобнее отлаживать в браузере.
+//
+// Все числа big-endian, str - длина uint8 и байты UTF-8, f32 - float32:
+//
+//	kind u8 (BinaryStateKind), version u8 (BinaryStateVersion),
+//	serverTime f64, tickMs f32 (метки для интерполяции, см. interpolation.go),
+//	phase u8 (0 - playing, 1 - intermission, 2 - waiting), phaseEndsInMs u32, noFireMs u32
+//	число игроков u16, для каждого:
+//	    id, nickname, color, class, weapon, spectating str
+//	    x, y, bodyAngle, aimAngle, radius f32
+//	    score i32, lives i16, team u8, флаги u8 (биты: 0 - dead, 1 - invulnerable, 2 - disconnected, 3 - bot,
+//	    4 - eliminated)
+//	    cooldownMs u32, noFireMs u32, respawnMs u32, canFireAt i64, lastProcessedSeq u32
+//	число снарядов u16, для каждого: id, ownerId, weapon str, x, y, radius f32, damage u8
+//	число команд u8, для каждой: team u8, score i32
+//	число событий ленты u16, для каждого: kind, playerId, playerName, targetId, targetName, detail str
+//	число отметок радара u16 (0 без -radar), для каждой: id str, x i32, y i32, team u8
+//
+// Клиент должен проверять version и не разбирать сообщения незнакомой версии. Версии:
+//
+//	1 - исходный формат (без байта version)
+//	2 - байт version, отметки радара в конце сообщения
+//	3 - serverTime и tickMs после version
+const (
+	BinaryStateKind    = 0x02
+	BinaryStateVersion = 3
+)
+
+// Кодировки состояния игры (параметр encoding при подключении)
+const (
+	EncodingJSON   = "json"
+	EncodingBinary = "binary"
+)
+
+const (
+	binaryStateDead = 1 << iota
+	binaryStateInvulnerable
+	binaryStateDisconnected
+	binaryStateBot
+	binaryStateEliminated
+)
+
+// isBinaryFrame сообщает, нужно ли отправить сообщение как BinaryMessage. Все JSON-сообщения
+// сервера - объекты и начинаются с '{', двоичные - с байта типа.
+func isBinaryFrame(msg []byte) bool {
+	return len(msg) > 0 && msg[0] != '{'
+}
+
+// stateEncoder дописывает значения в буфер двоичного состояния
+type stateEncoder struct {
+	buf []byte
+}
+
+func (e *stateEncoder) u8(v uint8)   { e.buf = append(e.buf, v) }
+func (e *stateEncoder) u16(v uint16) { e.buf = binary.BigEndian.AppendUint16(e.buf, v) }
+func (e *stateEncoder) u32(v uint32) { e.buf = binary.BigEndian.AppendUint32(e.buf, v) }
+func (e *stateEncoder) i64(v int64)  { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+func (e *stateEncoder) f32(v float64) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v)))
+}
+func (e *stateEncoder) f64(v float64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// ms записывает неотрицательную длительность в миллисекундах
+func (e *stateEncoder) ms(v int64) {
+	e.u32(uint32(max(0, min(v, math.MaxUint32))))
+}
+
+// str записывает строку, обрезая её до 255 байт
+func (e *stateEncoder) str(s string) {
+	if len(s) > math.MaxUint8 {
+		s = s[:math.MaxUint8]
+	}
+	e.u8(uint8(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// encodeBinaryState кодирует состояние игры в двоичный формат
+func encodeBinaryState(payload GameStatePayload) []byte {
+	e := &stateEncoder{buf: make([]byte, 0, 16+len(payload.Players)*96+len(payload.Projectiles)*40)}
+	e.u8(BinaryStateKind)
+	e.u8(BinaryStateVersion)
+	e.f64(payload.ServerTime)
+	e.f32(payload.TickMs)
+	switch payload.Phase {
+	case PhaseIntermission:
+		e.u8(1)
+	case PhaseWaiting:
+		e.u8(2)
+	default:
+		e.u8(0)
+	}
+	e.ms(payload.PhaseEndsInMs)
+	e.ms(payload.NoFireMs)
+
+	players := payload.Players[:min(len(payload.Players), math.MaxUint16)]
+	e.u16(uint16(len(players)))
+	for _, p := range players {
+		for _, s := range []string{p.ID, p.Nickname, p.Color, p.Class, p.Weapon, p.SpectatingID} {
+			e.str(s)
+		}
+		for _, f := range []float64{p.X, p.Y, p.BodyAngle, p.AimAngle, p.Radius} {
+			e.f32(f)
+		}
+		e.u32(uint32(int32(p.Score)))
+		e.u16(uint16(int16(max(math.MinInt16, min(p.Lives, math.MaxInt16)))))
+		e.u8(uint8(p.Team))
+		var flags uint8
+		if p.Dead {
+			flags |= binaryStateDead
+		}
+		if p.Invulnerable {
+			flags |= binaryStateInvulnerable
+		}
+		if p.Disconnected {
+			flags |= binaryStateDisconnected
+		}
+		if p.Bot {
+			flags |= binaryStateBot
+		}
+		if p.Eliminated {
+			flags |= binaryStateEliminated
+		}
+		e.u8(flags)
+		e.ms(p.CooldownMs)
+		e.ms(p.NoFireMs)
+		e.ms(p.RespawnMs)
+		e.i64(p.CanFireAt)
+		e.u32(p.LastProcessedSeq)
+	}
+
+	projectiles := payload.Projectiles[:min(len(payload.Projectiles), math.MaxUint16)]
+	e.u16(uint16(len(projectiles)))
+	for _, p := range projectiles {
+		e.str(p.ID)
+		e.str(p.OwnerID)
+		e.str(p.Weapon)
+		e.f32(p.X)
+		e.f32(p.Y)
+		e.f32(p.VisualRadius)
+		e.u8(uint8(max(0, min(p.Damage, math.MaxUint8))))
+	}
+
+	e.u8(uint8(min(len(payload.TeamScores), math.MaxUint8)))
+	written := 0
+	for team, score := range payload.TeamScores {
+		if written == math.MaxUint8 {
+			break
+		}
+		e.u8(uint8(team))
+		e.u32(uint32(int32(score)))
+		written++
+	}
+
+	events := payload.Events[:min(len(payload.Events), math.MaxUint16)]
+	e.u16(uint16(len(events)))
+	for _, ev := range events {
+		for _, s := range []string{string(ev.Kind), ev.PlayerID, ev.PlayerName, ev.TargetID, ev.TargetName, ev.Detail} {
+			e.str(s)
+		}
+	}
+
+	blips := payload.RadarBlips[:min(len(payload.RadarBlips), math.MaxUint16)]
+	e.u16(uint16(len(blips)))
+	for _, b := range blips {
+		e.str(b.ID)
+		e.u32(uint32(int32(b.X)))
+		e.u32(uint32(int32(b.Y)))
+		e.u8(uint8(b.Team))
+	}
+	return e.buf
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// stateDecoder читает двоичное состояние в тестах (обратная сторона stateEncoder)
+type stateDecoder struct {
+	t   *testing.T
+	buf []byte
+}
+
+func (d *stateDecoder) take(n int) []byte {
+	d.t.Helper()
+	if len(d.buf) < n {
+		d.t.Fatalf("двоичное состояние обрывается: нужно %d байт, осталось %d", n, len(d.buf))
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *stateDecoder) u8() uint8    { return d.take(1)[0] }
+func (d *stateDecoder) u16() uint16  { return binary.BigEndian.Uint16(d.take(2)) }
+func (d *stateDecoder) u32() uint32  { return binary.BigEndian.Uint32(d.take(4)) }
+func (d *stateDecoder) f32() float32 { return math.Float32frombits(d.u32()) }
+func (d *stateDecoder) f64() float64 { return math.Float64frombits(binary.BigEndian.Uint64(d.take(8))) }
+func (d *stateDecoder) str() string  { return string(d.take(int(d.u8()))) }
+
+// skipBody пропускает игроков, снаряды, команды и ленту, оставляя в буфере хвост после них
+func (d *stateDecoder) skipBody() {
+	for n := d.u16(); n > 0; n-- {
+		for i := 0; i < 6; i++ {
+			d.str()
+		}
+		d.take(5*4 + 4 + 2 + 1 + 1 + 3*4 + 8 + 4)
+	}
+	for n := d.u16(); n > 0; n-- {
+		d.str()
+		d.str()
+		d.str()
+		d.take(3*4 + 1)
+	}
+	for n := d.u8(); n > 0; n-- {
+		d.take(1 + 4)
+	}
+	for n := d.u16(); n > 0; n-- {
+		for i := 0; i < 6; i++ {
+			d.str()
+		}
+	}
+}
+
+func TestBinaryStateTimestamps(t *testing.T) {
+	payload := GameStatePayload{ServerTime: 12345.678, TickMs: 1000.0 / 60}
+	d := &stateDecoder{t: t, buf: encodeBinaryState(payload)}
+	d.take(2) // kind, version
+	if got := d.f64(); got != payload.ServerTime {
+		t.Errorf("serverTime = %v, ожидалось %v", got, payload.ServerTime)
+	}
+	if got := d.f32(); got != float32(payload.TickMs) {
+		t.Errorf("tickMs = %v, ожидалось %v", got, float32(payload.TickMs))
+	}
+}
+
+func TestBinaryStateRadar(t *testing.T) {
+	payload := GameStatePayload{
+		Players:     []*Player{{ID: "plr-1", X: 10, Y: 20}},
+		Projectiles: []*Projectile{{ID: "prj-1", OwnerID: "plr-1"}},
+		Events:      []FeedEvent{{PlayerID: "plr-1", Detail: "test"}},
+		TeamScores:  map[int]int{1: 3},
+		RadarBlips:  []RadarBlip{{ID: "plr-1", X: 10, Y: -20, Team: 1}, {ID: "plr-2", X: 3000, Y: 40}},
+	}
+	d := &stateDecoder{t: t, buf: encodeBinaryState(payload)}
+	if kind, version := d.u8(), d.u8(); kind != BinaryStateKind || version != BinaryStateVersion {
+		t.Fatalf("заголовок %#x/%d, ожидался %#x/%d", kind, version, BinaryStateKind, BinaryStateVersion)
+	}
+	d.take(8 + 4 + 1 + 4 + 4) // serverTime, tickMs, phase, phaseEndsInMs, noFireMs
+	d.skipBody()
+
+	if n := d.u16(); n != uint16(len(payload.RadarBlips)) {
+		t.Fatalf("отметок радара %d, ожидалось %d", n, len(payload.RadarBlips))
+	}
+	for _, want := range payload.RadarBlips {
+		got := RadarBlip{ID: d.str(), X: int(int32(d.u32())), Y: int(int32(d.u32())), Team: int(d.u8())}
+		if got != want {
+			t.Fatalf("отметка %+v, ожидалась %+v", got, want)
+		}
+	}
+	if len(d.buf) != 0 {
+		t.Fatalf("после отметок радара осталось %d байт", len(d.buf))
+	}
+}
+
+// deflatedSize - размер сообщения после сжатия, как при permessage-deflate (см. compression.go)
+func deflatedSize(t *testing.T, msg []byte) int {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(msg)
+	w.Close()
+	return buf.Len()
+}
+
+// Двоичное состояние должно быть заметно меньше JSON и без сжатия, и со сжатием
+func TestBinaryStateSize(t *testing.T) {
+	for _, size := range []struct{ players, projectiles int }{{4, 10}, {16, 60}} {
+		players, projs := scatterEntities(size.players, size.projectiles)
+		payload := GameStatePayload{Phase: PhasePlaying, ServerTime: 1234.5, TickMs: 1000.0 / 60}
+		for _, p := range players {
+			p.Nickname, p.Color, p.Class, p.Weapon, p.Lives = "Игрок "+p.ID, "#ff8800", DefaultTankClass, DefaultWeapon, 3
+			payload.Players = append(payload.Players, p)
+		}
+		for _, proj := range projs {
+			proj.OwnerID, proj.Weapon, proj.Damage, proj.VisualRadius = "plr-0", DefaultWeapon, 1, 5
+			payload.Projectiles = append(payload.Projectiles, proj)
+		}
+
+		jsonState, err := json.Marshal(ServerMessage{Type: "gameState", Payload: payload})
+		if err != nil {
+			t.Fatal(err)
+		}
+		binaryState := encodeBinaryState(payload)
+		jsonDeflated, binaryDeflated := deflatedSize(t, jsonState), deflatedSize(t, binaryState)
+		name := fmt.Sprintf("%d игроков, %d снарядов", size.players, size.projectiles)
+		t.Logf("%s: JSON %d байт (сжатый %d), двоичное %d байт (сжатое %d)",
+			name, len(jsonState), jsonDeflated, len(binaryState), binaryDeflated)
+
+		if len(binaryState)*2 > len(jsonState) {
+			t.Errorf("%s: двоичное состояние %d байт - больше половины JSON (%d)", name, len(binaryState), len(jsonState))
+		}
+		if binaryDeflated >= jsonDeflated {
+			t.Errorf("%s: сжатое двоичное состояние %d байт не меньше сжатого JSON (%d)", name, binaryDeflated, jsonDeflated)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// --- Боты ---
+
+// Бот - танк без соединения (Conn и MessageChan пустые, Bot = true), которым управляет сервер.
+// В начале каждого тика updateBots выставляет ботам ввод так же, как его прислал бы клиент: бот
+// едет к ближайшему противнику, держась от него на расстоянии BotPreferredDistance, наводит на него
+// башню и стреляет, как только пушка перезарядится. Дальше бот проходит обычную обработку игрока
+// в updateGameLogic: те же движение, столкновения, урон и таблица очков.
+//
+// Заметив новую цель, бот стреляет не сразу, а через время реакции, и целится с разбросом.
+// Оба параметра зависят от сложности ботов (см. difficulty.go).
+
+const (
+	MaxBots              = 32  // Сколько ботов можно запустить флагом -bots
+	BotPreferredDistance = 220 // На каком расстоянии от цели бот перестаёт к ней приближаться
+	BotRetreatDistance   = 120 // Ближе этого бот отъезжает от цели
+	BotAxisDeadzone      = 10  // Смещение по оси меньше этого не двигает бота вдоль неё
+)
+
+// botBrain - состояние бота между тиками
+type botBrain struct {
+	targetID  string    // Текущая цель ("" - целей нет)
+	noticedAt time.Time // Когда бот заметил цель (игровые часы)
+	aimNoise  float64   // Отклонение прицела для следующего выстрела, радианы
+}
+
+// spawnBot создаёт бота и ставит его на арену. Вызывается под game.mutex (или до запуска игровых циклов).
+func (game *GameState) spawnBot() *Player {
+	botID := generateID("bot", &nextPlayerID)
+	bot := &Player{
+		ID:       botID,
+		Class:    DefaultTankClass,
+		Team:     game.assignTeam(),
+		Color:    randomColor(),
+		Nickname: "Bot " + botID,
+		Layer:    LayerPlayer,
+		Bot:      true,
+	}
+	game.spawnPlayer(bot)
+	applyWeapon(bot, weapons[DefaultWeapon])
+	game.Players[botID] = bot
+	game.scoreboardDirty = true
+	slog.Info("Создан бот", "player_id", botID)
+	return bot
+}
+
+// updateBots выставляет ввод всем живым ботам. Вызывается под game.mutex перед обновлением игроков.
+func (game *GameState) updateBots() {
+	difficulty := game.currentBotDifficulty()
+	for _, bot := range game.Players {
+		if !bot.Bot || bot.Dead {
+			continue
+		}
+		if bot.brain == nil {
+			bot.brain = &botBrain{} // Бот из сценария
+		}
+		game.steerBot(bot, difficulty)
+	}
+}
+
+// nearestEnemy возвращает ближайшего к боту противника, в которого можно попасть (nil - таких нет)
+func (game *GameState) nearestEnemy(bot *Player) *Player {
+	var nearest *Player
+	nearestDist := math.Inf(1)
+	for _, other := range game.Players {
+		if other.ID == bot.ID || !solid(other) || other.Disconnected || teammates(bot, other) {
+			continue
+		}
+		if dist := math.Hypot(other.X-bot.X, other.Y-bot.Y); dist < nearestDist {
+			nearest, nearestDist = other, dist
+		}
+	}
+	return nearest
+}
+
+// steerBot выбирает цель, движение, прицел и выстрел бота на этот тик
+func (game *GameState) steerBot(bot *Player, difficulty BotDifficulty) {
+	brain := bot.brain
+	bot.Input = PlayerInput{}
+
+	target := game.nearestEnemy(bot)
+	if target == nil {
+		brain.targetID = ""
+		return
+	}
+	if target.ID != brain.targetID {
+		brain.targetID = target.ID
+		brain.noticedAt = game.gameNow()
+		brain.aimNoise = (rand.Float64()*2 - 1) * difficulty.AimNoise
+	}
+
+	// Едем к цели, пока она далеко, и отъезжаем, если она слишком близко
+	dx, dy := target.X-bot.X, target.Y-bot.Y
+	dist := math.Hypot(dx, dy)
+	direction := 0.0
+	switch {
+	case dist > BotPreferredDistance:
+		direction = 1
+	case dist < BotRetreatDistance:
+		direction = -1
+	}
+	if direction != 0 {
+		if mx := dx * direction; math.Abs(mx) > BotAxisDeadzone {
+			bot.Input.Right, bot.Input.Left = mx > 0, mx < 0
+		}
+		if my := dy * direction; math.Abs(my) > BotAxisDeadzone {
+			bot.Input.Down, bot.Input.Up = my > 0, my < 0
+		}
+	}
+
+	// Точка прицела - на цели, но с разбросом; башню поворачивает updateAim
+	angle := math.Atan2(dy, dx) + brain.aimNoise
+	bot.Input.AimX = bot.X + math.Cos(angle)*dist
+	bot.Input.AimY = bot.Y + math.Sin(angle)*dist
+
+	// Стреляем после времени реакции и только с перезаряженной пушкой
+	if game.gameNow().Sub(brain.noticedAt) < difficulty.ReactionTime {
+		return
+	}
+	if block, _ := game.fireBlock(bot); block == "" {
+		bot.WantsToShoot = true
+		brain.aimNoise = (rand.Float64()*2 - 1) * difficulty.AimNoise // Следующий выстрел - с новым разбросом
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "math"
+
+// --- Заряженные выстрелы ---
+
+// Клиент может присылать в ShootCommand заряд (0..1): чем дольше игрок держит кнопку выстрела,
+// тем больше заряд. Заряженный снаряд летит быстрее и отнимает больше жизней. Заряд 0 (и старые
+// клиенты, которые его не присылают) - обычный выстрел: ProjectileSpeed и ProjectileDamage.
+
+const (
+	MaxChargeSpeedMultiplier = 2 // Во сколько раз быстрее обычного летит снаряд с полным зарядом
+	MaxChargeDamage          = 3 // Урон снаряда с полным зарядом
+)
+
+// clampCharge приводит заряд из команды клиента к диапазону [0, 1] (NaN - без заряда)
+func clampCharge(charge float64) float64 {
+	if !isFinite(charge) || charge < 0 {
+		return 0
+	}
+	return math.Min(charge, 1)
+}
+
+// chargedSpeed возвращает множитель скорости снаряда для заряда charge (не меньше 1)
+func chargedSpeed(charge float64) float64 {
+	return 1 + (MaxChargeSpeedMultiplier-1)*charge
+}
+
+// chargedDamage возвращает урон снаряда для заряда charge (не меньше ProjectileDamage)
+func chargedDamage(charge float64) int {
+	return ProjectileDamage + int(math.Round((MaxChargeDamage-ProjectileDamage)*charge))
+}
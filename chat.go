@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Чат ---
+
+// Клиент отправляет {"action": "chat", "payload": {"text": "...", "team": false}}, сервер очищает
+// текст и рассылает всем сообщение "chat" с ником отправителя. С "team": true в командном режиме
+// сообщение получают только союзники (вне командного режима флаг игнорируется).
+//
+// У чата своё ведро токенов (ChatRate и ChatBurst), отдельное от общего лимита сообщений:
+// частый ввод движения не мешает писать, а спам в чат отбрасывается с ошибкой chat_rate_limited.
+// Рассылка идёт через неблокирующую очередь сообщений игрока и не задерживает игровой цикл.
+
+const (
+	MaxChatLength = 200 // Длина сообщения чата в символах (рунах), длиннее - обрезается
+	ChatRate      = 1.0 // Сообщений чата в секунду в среднем
+	ChatBurst     = 5   // Сколько сообщений чата можно отправить подряд
+)
+
+// ChatCommand - сообщение чата от клиента
+type ChatCommand struct {
+	Text string `json:"text"`
+	Team bool   `json:"team"` // Только своей команде
+}
+
+// ChatPayload - сообщение чата для клиентов
+type ChatPayload struct {
+	PlayerID string `json:"playerId"`
+	Nickname string `json:"nickname"`
+	Text     string `json:"text"`
+	Team     int    `json:"team,omitempty"` // Команда, если сообщение только для неё
+	Time     int64  `json:"time"`           // Время сервера, мс Unix
+}
+
+// sanitizeChat убирает из сообщения управляющие и невидимые символы, схлопывает пробелы и
+// обрезает текст до MaxChatLength. Пустая строка - сообщение не отправляется.
+func sanitizeChat(raw string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case r == utf8.RuneError || !unicode.IsPrint(r):
+			return -1
+		}
+		return r
+	}, raw)
+	text := strings.Join(strings.Fields(cleaned), " ")
+	if utf8.RuneCountInString(text) > MaxChatLength {
+		text = strings.TrimSpace(string([]rune(text)[:MaxChatLength]))
+	}
+	return text
+}
+
+// chat рассылает сообщение игрока p. Вызывается под game.mutex.
+func (game *GameState) chat(p *Player, cmd ChatCommand) {
+	text := sanitizeChat(cmd.Text)
+	if text == "" {
+		return
+	}
+	payload := ChatPayload{PlayerID: p.ID, Nickname: p.Nickname, Text: text, Time: time.Now().UnixMilli()}
+	if !cmd.Team || !config.TeamMode || p.Team == 0 {
+		slog.Info("Сообщение чата", "player_id", p.ID, "text", text)
+		game.broadcastMessage(ServerMessage{Type: "chat", Payload: payload})
+		return
+	}
+	payload.Team = p.Team
+	msgBytes, err := json.Marshal(ServerMessage{Type: "chat", Payload: payload})
+	if err != nil {
+		slog.Error("Ошибка маршалинга сообщения", "type", "chat", "err", err)
+		return
+	}
+	slog.Info("Сообщение чата команде", "player_id", p.ID, "team", p.Team, "text", text)
+	for _, other := range game.Players {
+		if other == p || teammates(p, other) {
+			queueMessage(other, msgBytes)
+		}
+	}
+}
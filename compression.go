@@ -0,0 +1,80 @@
+package main
+
+import (
+	"compress/flate"
+	"log/slog"
+	"net"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// --- Сжатие сообщений (permessage-deflate) ---
+
+// Полное состояние в JSON хорошо сжимается. Сжатие включается только для клиентов,
+// которые сами предложили расширение permessage-deflate (все браузеры делают это сами).
+// Сжатие стоит процессорного времени на каждое сообщение, поэтому отключается флагом -compression=false.
+//
+// Экономию видно по метрикам: tanki_message_bytes_sent_total - байт в сообщениях до сжатия,
+// tanki_bytes_written_total - байт, фактически записанных в сокеты.
+
+// Пределы уровня сжатия, которые принимает gorilla/websocket
+const (
+	MinCompressionLevel = flate.HuffmanOnly     // -2: только кодирование Хаффмана
+	MaxCompressionLevel = flate.BestCompression // 9
+)
+
+var (
+	messageBytesSentTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_message_bytes_sent_total",
+		Help: "Байт в сообщениях, отправленных клиентам, до сжатия.",
+	})
+	bytesWrittenTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_bytes_written_total",
+		Help: "Байт, записанных в сокеты основного HTTP-сервера (после сжатия, включая заголовки и HTTP-ответы).",
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(messageBytesSentTotal, bytesWrittenTotal)
+}
+
+// setupCompression - включает согласование сжатия для входящих и ретранслируемых соединений
+func setupCompression() {
+	upgrader.EnableCompression = config.Compression
+	relayDialer.EnableCompression = config.Compression
+}
+
+// configureCompression - задаёт уровень сжатия соединению, если клиент согласовал расширение
+func configureCompression(conn *websocket.Conn) {
+	if !config.Compression {
+		return
+	}
+	if err := conn.SetCompressionLevel(config.CompressionLevel); err != nil { // Уровень уже проверен в validate
+		slog.Warn("Не удалось задать уровень сжатия", "remote_addr", conn.RemoteAddr().String(), "err", err)
+	}
+}
+
+// countingListener - считает байты, записанные во все принятые соединения
+type countingListener struct {
+	net.Listener
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn}, nil
+}
+
+// countingConn - соединение, учитывающее записанные байты в bytesWrittenTotal
+type countingConn struct {
+	net.Conn
+}
+
+func (c countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	bytesWrittenTotal.Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"compress/flate"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- Конфигурация ---
+
+// Config - настройки сервера, задаваемые при запуске флагами или переменными окружения (см. applyEnv)
+type Config struct {
+	Addr            string // Адрес HTTP-сервера
+	MapPath         string // JSON-файл карты (пусто - пустая арена по умолчанию)
+	MapRotation     string // JSON-файлы карт через запятую, сменяющихся между раундами (заменяет MapPath)
+	RotationOrder   string // Порядок ротации: RotationSequential или RotationRandom
+	ScenarioPath    string // JSON-файл сценария с начальными игроками и снарядами (пусто - пустая арена)
+	SnapshotEnabled bool   // Отдавать отладочный снимок арены GET /snapshot.png
+	PprofAddr       string // Админский адрес для pprof и замеров фаз тика (пусто - профилирование выключено)
+	AdminToken      string // Токен для ручек /admin (пусто - админка выключена)
+	RelayUpstream   string // WebSocket-адрес игрового сервера, на который ретранслируются клиенты (пусто - своя игра)
+	RelayTrusted    string // Адреса и подсети ретрансляторов через запятую, которым верим X-Forwarded-For (пусто - никому)
+	SpawnFacing     string // Куда смотрит танк при появлении: SpawnFaceCenter или SpawnFaceEnemy
+
+	TickRate      int     // Обновлений логики в секунду
+	BroadcastRate int     // Отправок состояния клиентам в секунду
+	ArenaWidth    int     // Ширина арены, если карта не задаёт свою
+	ArenaHeight   int     // Высота арены, если карта не задаёт свою
+	PlayerSpeed   float64 // Скорость стандартного танка, пикселей в секунду
+	InitialLives  int     // Жизней стандартного танка при появлении
+	FireRate      float64 // Выстрелов в секунду у стандартной пушки
+
+	LoopMode        string // Режим шага симуляции: LoopVariable или LoopFixed
+	MaxStepsPerLoop int    // Максимум фиксированных шагов за итерацию цикла (защита от "спирали смерти")
+
+	MinSpawnDistance    float64 // Минимальное расстояние от точки появления до живого противника, px
+	SpawnWeightExponent float64 // Показатель веса точки появления по удалённости от противников (0 - первая подходящая)
+
+	TeamMode           bool // Командный режим: игроки делятся на TeamCount команд
+	FriendlyFire       bool // Снаряды попадают в союзников (очков за это не дают)
+	AutoRebalance      bool // Переводить игроков между командами при перекосе
+	RebalanceThreshold int  // Допустимая разница в числе игроков между командами
+
+	TankCollision       bool // Танки расталкивают друг друга при столкновении
+	TeammatePassThrough bool // Союзники проезжают друг сквозь друга (при TankCollision в командном режиме)
+
+	DamageIndicators     bool          // Сообщать пострадавшему направление, откуда пришёл урон
+	HitLingering         bool          // Снаряды попадают в танки отключившихся игроков, пока те не убраны с арены
+	UniqueNicknames      bool          // Отклонять никнейм, уже занятый другим игроком на арене
+	Earshot              float64       // Дальше этого расстояния звуки событий не отправляются (0 - слышны всем)
+	ViewRadius           float64       // Дальше этого расстояния танки и снаряды не попадают в состояние игрока (0 - видно всё)
+	Radar                bool          // Добавлять в состояние игры положения всех живых танков для миникарты
+	SpawnInvulnerability time.Duration // Сколько танк неуязвим после появления (0 - не защищён)
+	SpawnProtectionBreak string        // Что снимает неуязвимость раньше срока: ProtectionBreakFire, ProtectionBreakMove или ProtectionBreakTimer
+
+	TimeScale float64 // Начальный масштаб времени симуляции (меняется на лету через /debug/timescale)
+
+	ScoreLimit   int           // Очков для победы в раунде (0 - раунд не заканчивается)
+	RoundTime    time.Duration // Длительность раунда, после которой побеждает лучший по счёту (0 - без ограничения)
+	Intermission time.Duration // Перерыв между раундами (0 - следующий раунд начинается сразу)
+	LastStanding bool          // Погибший выбывает до конца раунда, побеждает последний живой танк (команда)
+	MinPlayers   int           // Сколько игроков (включая ботов) нужно для начала раунда (0 - без ожидания)
+	RespawnDelay time.Duration // Через сколько погибший танк возрождается
+	MaxDeadTime  time.Duration // Дольше этого погибший не ждёт даже при ручном возрождении
+
+	ChainExplosions      bool // Взрыв подрывает взрывоопасные снаряды в радиусе
+	ProjectileCollisions bool // Столкнувшиеся снаряды противников гасят друг друга
+
+	ProjectileLifetime time.Duration // Дольше этого (по игровым часам) снаряд не летает (0 - до края арены)
+	ProjectileRange    float64       // Дальше этого снаряд не летает, пикселей (0 - до края арены)
+
+	MaxProjectilesPerPlayer int           // Лимит снарядов одного игрока в полёте (0 - без ограничения)
+	MaxProjectilesTotal     int           // Общий лимит снарядов на арене (0 - без ограничения)
+	ShotQueue               bool          // Ставить выстрел в очередь при достижении лимита вместо отказа
+	ShotQueueWindow         time.Duration // Сколько выстрел может ждать освобождения слота
+
+	DespawnOnDisconnect bool // Убирать снаряды игрока, когда он отключается (даже если танк ещё на арене)
+	DespawnOnDeath      bool // Убирать снаряды игрока, когда его танк уничтожен
+
+	BorderMisses  bool // Считать снаряды, улетевшие за край арены, промахами владельца
+	DeadlyBorders bool // Танк, коснувшийся обрыва на краю арены (MapDef.Pits), погибает
+
+	KillCredit   string // Кому засчитывается попадание снаряда, сменившего владельца: CreditShooter, CreditLastDeflector или CreditSplit
+	LivesPerKill int    // Сколько жизней получает игрок за убийство (0 - не получает)
+	MaxLives     int    // Предел жизней при получении за убийства (0 - стартовые жизни класса)
+
+	AdaptiveBots   bool          // Подстраивать сложность ботов под успехи игроков
+	BotReactionMin time.Duration // Время реакции самых сильных ботов
+	BotReactionMax time.Duration // Время реакции самых слабых ботов
+	BotAimNoiseMin float64       // Разброс прицела самых сильных ботов, радианы
+	BotAimNoiseMax float64       // Разброс прицела самых слабых ботов, радианы
+	Bots           int           // Сколько ботов добавить в комнату по умолчанию при запуске
+
+	LogSample string // Прореживание журнала частых событий: "shot=10,hit=5" - каждая 10-я и 5-я строка
+	LogLevel  string // Нижний уровень журнала: debug, info, warn или error
+	LogFormat string // Формат журнала: LogFormatText или LogFormatJSON
+
+	HighlightsDir   string        // Каталог для записей ярких моментов (пусто - запись выключена)
+	HighlightBuffer time.Duration // Сколько секунд до события попадает в запись
+	LeaderboardPath string        // JSON-файл таблицы рекордов (пусто - таблица только в памяти)
+
+	NoFireDuration  time.Duration // Разминка без стрельбы в начале раунда (0 - выключена)
+	NoFireOnRespawn bool          // Запрещать стрельбу на NoFireDuration и после каждого появления
+
+	// Буферы и лимиты WebSocket. Буферы выделяются на каждое соединение, поэтому слишком большие
+	// значения расходуют память, а слишком маленький ReadLimit обрывает соединение на крупных сообщениях.
+	MaxConnections   int           // Сколько соединений обслуживается одновременно (0 - без ограничения)
+	MaxRooms         int           // Сколько комнат может существовать одновременно, включая основную (0 - без ограничения)
+	MaxPlayers       int           // Сколько игроков (не ботов) может быть в одной комнате (0 - без ограничения)
+	MessageRate      float64       // Сколько сообщений в секунду принимается от одного клиента
+	MessageBurst     int           // Сколько сообщений подряд можно прислать сверх MessageRate
+	FloodKick        time.Duration // Через сколько непрерывного превышения лимита клиент отключается (0 - не отключать)
+	PingInterval     time.Duration // Как часто writer отправляет клиенту ping
+	PongTimeout      time.Duration // Сколько ждать сообщения или pong, прежде чем считать соединение мёртвым
+	ReconnectGrace   time.Duration // Сколько отключившийся игрок ждёт переподключения с токеном сессии (0 - удаляется сразу)
+	SessionConflict  string        // Подключение по токену игрока с живым соединением: SessionTakeover или SessionReject
+	DisconnectLinger time.Duration // Сколько танк отключившегося остаётся на арене, чтобы клиенты плавно его убрали (0 - удаляется сразу)
+	ReadBufferSize   int           // Буфер чтения, байт (по умолчанию 1024)
+	WriteBufferSize  int           // Буфер записи, байт (по умолчанию 1024)
+	ReadLimit        int64         // Максимальный размер входящего сообщения, байт (по умолчанию 512)
+
+	Compression      bool // Сжимать сообщения (permessage-deflate) для клиентов, которые его поддерживают
+	CompressionLevel int  // Уровень сжатия от MinCompressionLevel до MaxCompressionLevel
+
+	MaxStateMessageSize int // Состояние игры больше этого размера отправляется частями (0 - одним сообщением)
+
+	DeltaState     bool // Рассылать только изменения состояния (полное - при подключении и раз в FullStateEvery рассылок)
+	FullStateEvery int  // Через сколько рассылок изменений отправлять полное состояние
+}
+
+// defaultConfig - настройки по умолчанию
+func defaultConfig() Config {
+	return Config{
+		Addr:                 ":8080",
+		TickRate:             60,
+		LoopMode:             LoopVariable,
+		MaxStepsPerLoop:      5,
+		BroadcastRate:        30,
+		ArenaWidth:           800,
+		ArenaHeight:          600,
+		PlayerSpeed:          150,
+		InitialLives:         15,
+		FireRate:             2,
+		SpawnFacing:          SpawnFaceCenter,
+		MinSpawnDistance:     150,
+		SpawnProtectionBreak: ProtectionBreakFire,
+		RotationOrder:        RotationSequential,
+		LogLevel:             "info",
+		LogFormat:            LogFormatText,
+		KillCredit:           CreditShooter,
+		ScoreLimit:           DefaultScoreLimit,
+		Intermission:         DefaultIntermission,
+		TimeScale:            1,
+		RespawnDelay:         time.Second * 3,
+		MaxDeadTime:          time.Second * 30,
+		DamageIndicators:     true,
+		HitLingering:         true,
+		UniqueNicknames:      true,
+		TeammatePassThrough:  true,
+		HighlightBuffer:      time.Second * 10,
+		BotReactionMin:       time.Millisecond * 150,
+		BotReactionMax:       time.Millisecond * 800,
+		BotAimNoiseMin:       0.02,
+		BotAimNoiseMax:       0.3,
+		RebalanceThreshold:   1,
+		MaxConnections:       256,
+		MaxRooms:             64,
+		MessageRate:          120,
+		MessageBurst:         60,
+		PingInterval:         time.Second * 2,
+		PongTimeout:          time.Second * 6,
+		ReconnectGrace:       time.Second * 30,
+		SessionConflict:      SessionTakeover,
+		ReadBufferSize:       1024,
+		WriteBufferSize:      1024,
+		ReadLimit:            512,
+		MaxProjectilesTotal:  500,
+		ShotQueueWindow:      time.Millisecond * 300,
+		DespawnOnDisconnect:  true,
+		Compression:          true,
+		CompressionLevel:     flate.BestSpeed,
+		FullStateEvery:       60, // 2 секунды при стандартной частоте рассылки
+	}
+}
+
+// config - действующие настройки сервера (заполняются в main до запуска игровых циклов)
+var config = defaultConfig()
+
+// registerFlags объявляет флаги командной строки для настроек
+func (c *Config) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "адрес HTTP-сервера")
+	fs.StringVar(&c.MapPath, "map", c.MapPath, "путь к JSON-файлу карты (по умолчанию пустая арена)")
+	fs.StringVar(&c.MapRotation, "map-rotation", c.MapRotation, "карты через запятую, сменяющиеся после каждого раунда")
+	fs.StringVar(&c.RotationOrder, "rotation-order", c.RotationOrder, "порядок ротации карт: sequential или random")
+	fs.StringVar(&c.ScenarioPath, "scenario", c.ScenarioPath, "путь к JSON-файлу сценария с начальными игроками и снарядами")
+	fs.BoolVar(&c.SnapshotEnabled, "snapshot", c.SnapshotEnabled, "включить отладочный снимок арены GET /snapshot.png")
+	fs.StringVar(&c.PprofAddr, "pprof", c.PprofAddr, "админский адрес для pprof, например localhost:6060 (включает замеры фаз тика)")
+	fs.StringVar(&c.AdminToken, "admin-token", c.AdminToken, "токен для ручек /admin в заголовке X-Admin-Token (пусто - админка выключена)")
+	fs.StringVar(&c.RelayUpstream, "relay-upstream", c.RelayUpstream, "ретранслировать клиентов на игровой сервер, например ws://game:8080/ws")
+	fs.StringVar(&c.RelayTrusted, "relay-trusted", c.RelayTrusted, "адреса и подсети ретрансляторов через запятую, например 10.0.0.5,10.1.0.0/16: только их X-Forwarded-For считается адресом клиента")
+	fs.IntVar(&c.TickRate, "tick-rate", c.TickRate, "обновлений логики в секунду")
+	fs.StringVar(&c.LoopMode, "loop-mode", c.LoopMode, "шаг симуляции: variable (по настенным часам) или fixed (фиксированные шаги с накопителем)")
+	fs.IntVar(&c.MaxStepsPerLoop, "max-steps-per-loop", c.MaxStepsPerLoop, "сколько фиксированных шагов можно сделать за итерацию при отставании (режим fixed)")
+	fs.IntVar(&c.BroadcastRate, "broadcast-rate", c.BroadcastRate, "отправок состояния клиентам в секунду")
+	fs.IntVar(&c.ArenaWidth, "arena-width", c.ArenaWidth, "ширина арены, если карта не задаёт свою")
+	fs.IntVar(&c.ArenaHeight, "arena-height", c.ArenaHeight, "высота арены, если карта не задаёт свою")
+	fs.Float64Var(&c.PlayerSpeed, "player-speed", c.PlayerSpeed, "скорость стандартного танка, пикселей в секунду")
+	fs.IntVar(&c.InitialLives, "initial-lives", c.InitialLives, "жизней стандартного танка при появлении")
+	fs.Float64Var(&c.FireRate, "fire-rate", c.FireRate, "выстрелов в секунду у стандартной пушки")
+	fs.StringVar(&c.SpawnFacing, "spawn-facing", c.SpawnFacing, "куда смотрит танк при появлении: center или nearestEnemy")
+	fs.Float64Var(&c.MinSpawnDistance, "min-spawn-distance", c.MinSpawnDistance, "минимальное расстояние от точки появления до живого противника, px (на маленьких картах стоит уменьшить)")
+	fs.Float64Var(&c.SpawnWeightExponent, "spawn-weight-exponent", c.SpawnWeightExponent, "случайный выбор точки появления с весом расстояние^N до противников (0 - первая подходящая)")
+	fs.BoolVar(&c.TeamMode, "teams", c.TeamMode, "командный режим")
+	fs.BoolVar(&c.FriendlyFire, "friendly-fire", c.FriendlyFire, "снаряды попадают в союзников в командном режиме")
+	fs.BoolVar(&c.AutoRebalance, "auto-rebalance", c.AutoRebalance, "автоматически выравнивать команды")
+	fs.IntVar(&c.RebalanceThreshold, "rebalance-threshold", c.RebalanceThreshold, "допустимая разница в числе игроков между командами")
+	fs.BoolVar(&c.TankCollision, "tank-collision", c.TankCollision, "танки расталкивают друг друга при столкновении")
+	fs.BoolVar(&c.TeammatePassThrough, "teammate-pass-through", c.TeammatePassThrough, "союзники проезжают друг сквозь друга при tank-collision")
+	fs.BoolVar(&c.DamageIndicators, "damage-indicators", c.DamageIndicators, "сообщать пострадавшему направление урона")
+	fs.BoolVar(&c.HitLingering, "hit-lingering", c.HitLingering, "снаряды попадают в танки отключившихся игроков, пока те не убраны")
+	fs.BoolVar(&c.UniqueNicknames, "unique-nicknames", c.UniqueNicknames, "отклонять никнейм, уже занятый другим игроком (без учёта регистра)")
+	fs.Float64Var(&c.Earshot, "earshot", c.Earshot, "радиус слышимости звуков выстрелов, попаданий и взрывов, пикселей (0 - без ограничения)")
+	fs.DurationVar(&c.Intermission, "intermission", c.Intermission, "перерыв между раундами (0 - следующий раунд начинается сразу)")
+	fs.Float64Var(&c.ViewRadius, "view-radius", c.ViewRadius, "радиус видимости танков и снарядов в состоянии игры, пикселей (0 - без ограничения)")
+	fs.BoolVar(&c.Radar, "radar", c.Radar, "добавлять в состояние игры положения всех живых танков для миникарты (не урезаются view-radius)")
+	fs.DurationVar(&c.SpawnInvulnerability, "spawn-invulnerability", c.SpawnInvulnerability, "неуязвимость после появления, например 2s")
+	fs.StringVar(&c.SpawnProtectionBreak, "spawn-protection-break", c.SpawnProtectionBreak, "что снимает неуязвимость после появления: fire, move или timer")
+	fs.IntVar(&c.ScoreLimit, "score-limit", c.ScoreLimit, "очков для победы в раунде (0 - раунд не заканчивается)")
+	fs.DurationVar(&c.RoundTime, "round-time", c.RoundTime, "длительность раунда, например 5m; потом побеждает лучший по счёту (0 - без ограничения)")
+	fs.BoolVar(&c.LastStanding, "last-standing", c.LastStanding, "погибший выбывает до конца раунда, побеждает последний живой танк или команда")
+	fs.IntVar(&c.MinPlayers, "min-players", c.MinPlayers, "сколько игроков (включая ботов) нужно для начала раунда (0 - без ожидания)")
+	fs.Float64Var(&c.TimeScale, "time-scale", c.TimeScale, "масштаб времени симуляции: 0.5 - замедление вдвое, 2 - ускорение")
+	fs.DurationVar(&c.RespawnDelay, "respawn-delay", c.RespawnDelay, "через сколько погибший танк возрождается")
+	fs.DurationVar(&c.MaxDeadTime, "max-dead-time", c.MaxDeadTime, "максимальное время до возрождения при ручном возрождении")
+	fs.BoolVar(&c.ChainExplosions, "chain-explosions", c.ChainExplosions, "взрыв подрывает взрывоопасные снаряды в радиусе")
+	fs.BoolVar(&c.ProjectileCollisions, "projectile-collisions", c.ProjectileCollisions, "столкнувшиеся снаряды противников гасят друг друга")
+	fs.DurationVar(&c.ProjectileLifetime, "projectile-lifetime", c.ProjectileLifetime, "сколько летит снаряд, например 3s (0 - до края арены)")
+	fs.Float64Var(&c.ProjectileRange, "projectile-range", c.ProjectileRange, "дальность полёта снаряда, пикселей (0 - до края арены)")
+	fs.IntVar(&c.MaxProjectilesPerPlayer, "max-player-projectiles", c.MaxProjectilesPerPlayer, "сколько снарядов одного игрока может быть в полёте (0 - без ограничения)")
+	fs.IntVar(&c.MaxProjectilesTotal, "max-projectiles", c.MaxProjectilesTotal, "сколько снарядов может быть на арене (0 - без ограничения)")
+	fs.BoolVar(&c.ShotQueue, "shot-queue", c.ShotQueue, "при достижении лимита снарядов ставить выстрел в очередь вместо отказа")
+	fs.DurationVar(&c.ShotQueueWindow, "shot-queue-window", c.ShotQueueWindow, "сколько выстрел в очереди ждёт освобождения слота")
+	fs.BoolVar(&c.DespawnOnDisconnect, "despawn-on-disconnect", c.DespawnOnDisconnect, "убирать снаряды игрока, когда он отключается")
+	fs.BoolVar(&c.DespawnOnDeath, "despawn-on-death", c.DespawnOnDeath, "убирать снаряды игрока, когда его танк уничтожен")
+	fs.BoolVar(&c.BorderMisses, "border-misses", c.BorderMisses, "считать снаряды, улетевшие за край арены, промахами владельца")
+	fs.BoolVar(&c.DeadlyBorders, "deadly-borders", c.DeadlyBorders, "танк, коснувшийся обрыва на краю арены (pits в карте), погибает")
+	fs.StringVar(&c.KillCredit, "kill-credit", c.KillCredit, "кому засчитывать попадание отражённого снаряда: shooter, lastDeflector или split")
+	fs.IntVar(&c.LivesPerKill, "lives-per-kill", c.LivesPerKill, "жизней за убийство")
+	fs.IntVar(&c.MaxLives, "max-lives", c.MaxLives, "предел жизней, получаемых за убийства (0 - стартовые жизни класса)")
+	fs.BoolVar(&c.AdaptiveBots, "adaptive-bots", c.AdaptiveBots, "подстраивать сложность ботов под K/D игроков")
+	fs.DurationVar(&c.BotReactionMin, "bot-reaction-min", c.BotReactionMin, "время реакции самых сильных ботов")
+	fs.DurationVar(&c.BotReactionMax, "bot-reaction-max", c.BotReactionMax, "время реакции самых слабых ботов")
+	fs.Float64Var(&c.BotAimNoiseMin, "bot-aim-noise-min", c.BotAimNoiseMin, "разброс прицела самых сильных ботов, радианы")
+	fs.Float64Var(&c.BotAimNoiseMax, "bot-aim-noise-max", c.BotAimNoiseMax, "разброс прицела самых слабых ботов, радианы")
+	fs.IntVar(&c.Bots, "bots", c.Bots, "сколько ботов добавить в комнату по умолчанию при запуске")
+	fs.StringVar(&c.LogSample, "log-sample", c.LogSample, "писать в журнал каждое N-е событие типа, например shot=10,hit=5")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "нижний уровень журнала: debug, info, warn или error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "формат журнала: text или json")
+	fs.StringVar(&c.HighlightsDir, "highlights", c.HighlightsDir, "каталог для записей ярких моментов (по умолчанию запись выключена)")
+	fs.DurationVar(&c.HighlightBuffer, "highlight-buffer", c.HighlightBuffer, "сколько хранить состояний до яркого момента")
+	fs.StringVar(&c.LeaderboardPath, "leaderboard", c.LeaderboardPath, "JSON-файл таблицы рекордов (по умолчанию таблица не сохраняется между запусками)")
+	fs.DurationVar(&c.NoFireDuration, "no-fire", c.NoFireDuration, "разминка без стрельбы в начале раунда, например 3s")
+	fs.BoolVar(&c.NoFireOnRespawn, "no-fire-on-respawn", c.NoFireOnRespawn, "запрещать стрельбу на время разминки и после появления")
+	fs.IntVar(&c.MaxConnections, "max-connections", c.MaxConnections, "сколько соединений обслуживать одновременно (0 - без ограничения)")
+	fs.IntVar(&c.MaxRooms, "max-rooms", c.MaxRooms, "сколько комнат может существовать одновременно, включая основную (0 - без ограничения)")
+	fs.IntVar(&c.MaxPlayers, "max-players", c.MaxPlayers, "сколько игроков (не ботов) может быть в одной комнате (0 - без ограничения)")
+	fs.Float64Var(&c.MessageRate, "msg-rate", c.MessageRate, "сколько сообщений в секунду принимать от одного клиента; лишние отбрасываются")
+	fs.IntVar(&c.MessageBurst, "msg-burst", c.MessageBurst, "сколько сообщений подряд клиент может прислать сверх msg-rate")
+	fs.DurationVar(&c.FloodKick, "flood-kick", c.FloodKick, "отключать клиента, превышающего лимит сообщений дольше этого времени (0 - не отключать)")
+	fs.DurationVar(&c.PingInterval, "ping-interval", c.PingInterval, "как часто отправлять клиенту ping")
+	fs.DurationVar(&c.PongTimeout, "pong-timeout", c.PongTimeout, "отключать клиента, от которого столько времени нет ни сообщений, ни pong")
+	fs.DurationVar(&c.DisconnectLinger, "disconnect-linger", c.DisconnectLinger, "сколько танк отключившегося остаётся на арене для плавного исчезновения (0 - удалять сразу)")
+	fs.DurationVar(&c.ReconnectGrace, "reconnect-grace", c.ReconnectGrace, "сколько отключившийся танк ждёт переподключения с токеном сессии (0 - удалять сразу)")
+	fs.StringVar(&c.SessionConflict, "session-conflict", c.SessionConflict, "подключение с токеном игрока, у которого уже есть соединение: takeover (перехватить) или reject (отклонить)")
+	fs.IntVar(&c.ReadBufferSize, "read-buffer", c.ReadBufferSize, "размер буфера чтения WebSocket, байт")
+	fs.IntVar(&c.WriteBufferSize, "write-buffer", c.WriteBufferSize, "размер буфера записи WebSocket, байт")
+	fs.Int64Var(&c.ReadLimit, "read-limit", c.ReadLimit, "максимальный размер входящего сообщения, байт")
+	fs.BoolVar(&c.Compression, "compression", c.Compression, "сжимать сообщения (permessage-deflate) для клиентов, которые его поддерживают")
+	fs.IntVar(&c.CompressionLevel, "compression-level", c.CompressionLevel, "уровень сжатия: от -2 (только Хаффман) до 9 (максимальное)")
+	fs.IntVar(&c.MaxStateMessageSize, "max-state-size", c.MaxStateMessageSize, "отправлять состояние игры частями, если оно больше N байт (0 - одним сообщением)")
+	fs.BoolVar(&c.DeltaState, "delta-state", c.DeltaState, "рассылать только изменения состояния игры")
+	fs.IntVar(&c.FullStateEvery, "full-state-every", c.FullStateEvery, "через сколько рассылок изменений отправлять полное состояние")
+}
+
+// validate проверяет, что настройки имеют смысл
+func (c *Config) validate() error {
+	if c.TickRate < 1 || c.TickRate > 240 {
+		return fmt.Errorf("tick-rate должен быть от 1 до 240, получено %d", c.TickRate)
+	}
+	if c.LoopMode != LoopVariable && c.LoopMode != LoopFixed {
+		return fmt.Errorf("loop-mode должен быть %s или %s, получено %q", LoopVariable, LoopFixed, c.LoopMode)
+	}
+	if c.MaxStepsPerLoop < 1 || c.MaxStepsPerLoop > 60 {
+		return fmt.Errorf("max-steps-per-loop должен быть от 1 до 60, получено %d", c.MaxStepsPerLoop)
+	}
+	if c.BroadcastRate < MinUpdateRate || c.BroadcastRate > c.TickRate {
+		return fmt.Errorf("broadcast-rate должен быть от %d до tick-rate (%d), получено %d", MinUpdateRate, c.TickRate, c.BroadcastRate)
+	}
+	if c.ArenaWidth < 100 || c.ArenaWidth > 10000 || c.ArenaHeight < 100 || c.ArenaHeight > 10000 {
+		return fmt.Errorf("размер арены должен быть от 100 до 10000, получено %dx%d", c.ArenaWidth, c.ArenaHeight)
+	}
+	if !(c.PlayerSpeed > 0 && c.PlayerSpeed <= 2000) {
+		return fmt.Errorf("player-speed должен быть больше 0 и не больше 2000, получено %v", c.PlayerSpeed)
+	}
+	if c.InitialLives < 1 || c.InitialLives > 1000 {
+		return fmt.Errorf("initial-lives должен быть от 1 до 1000, получено %d", c.InitialLives)
+	}
+	if !(c.FireRate > 0 && c.FireRate <= 50) {
+		return fmt.Errorf("fire-rate должен быть больше 0 и не больше 50, получено %v", c.FireRate)
+	}
+	if c.SpawnFacing != SpawnFaceCenter && c.SpawnFacing != SpawnFaceEnemy {
+		return fmt.Errorf("неизвестное значение spawn-facing %q", c.SpawnFacing)
+	}
+	if !(c.MinSpawnDistance >= 0 && c.MinSpawnDistance <= 5000) {
+		return fmt.Errorf("min-spawn-distance должен быть от 0 до 5000, получено %v", c.MinSpawnDistance)
+	}
+	if !(c.SpawnWeightExponent >= 0 && c.SpawnWeightExponent <= 8) {
+		return fmt.Errorf("spawn-weight-exponent должен быть от 0 до 8, получено %v", c.SpawnWeightExponent)
+	}
+	if c.RotationOrder != RotationSequential && c.RotationOrder != RotationRandom {
+		return fmt.Errorf("неизвестное значение rotation-order %q", c.RotationOrder)
+	}
+	if c.RelayUpstream != "" {
+		u, err := url.Parse(c.RelayUpstream)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" || u.RawQuery != "" {
+			return fmt.Errorf("relay-upstream должен быть адресом ws:// или wss:// без строки запроса, получено %q", c.RelayUpstream)
+		}
+	}
+	if _, err := parseRelayTrusted(c.RelayTrusted); err != nil {
+		return fmt.Errorf("relay-trusted: %w", err)
+	}
+	if c.RebalanceThreshold < 1 {
+		return fmt.Errorf("rebalance-threshold должен быть не меньше 1, получено %d", c.RebalanceThreshold)
+	}
+	if c.Earshot < 0 || math.IsNaN(c.Earshot) {
+		return fmt.Errorf("earshot не может быть отрицательным, получено %v", c.Earshot)
+	}
+	if c.ViewRadius < 0 || math.IsNaN(c.ViewRadius) {
+		return fmt.Errorf("view-radius не может быть отрицательным, получено %v", c.ViewRadius)
+	}
+	if c.SpawnInvulnerability < 0 || c.SpawnInvulnerability > time.Minute {
+		return fmt.Errorf("spawn-invulnerability должен быть от 0 до 1m, получено %v", c.SpawnInvulnerability)
+	}
+	if err := validTimeScale(c.TimeScale); err != nil {
+		return err
+	}
+	if c.SpawnProtectionBreak != ProtectionBreakFire && c.SpawnProtectionBreak != ProtectionBreakMove && c.SpawnProtectionBreak != ProtectionBreakTimer {
+		return fmt.Errorf("неизвестное значение spawn-protection-break %q", c.SpawnProtectionBreak)
+	}
+	if c.ScoreLimit < 0 {
+		return fmt.Errorf("score-limit не может быть отрицательным, получено %d", c.ScoreLimit)
+	}
+	if c.RoundTime < 0 {
+		return fmt.Errorf("round-time не может быть отрицательным, получено %v", c.RoundTime)
+	}
+	if c.Intermission < 0 {
+		return fmt.Errorf("intermission не может быть отрицательным, получено %v", c.Intermission)
+	}
+	if c.MinPlayers < 0 {
+		return fmt.Errorf("min-players не может быть отрицательным, получено %d", c.MinPlayers)
+	}
+	if c.RespawnDelay < 0 || c.MaxDeadTime < c.RespawnDelay {
+		return fmt.Errorf("нужно 0 <= respawn-delay <= max-dead-time, получено %v и %v", c.RespawnDelay, c.MaxDeadTime)
+	}
+	if c.KillCredit != CreditShooter && c.KillCredit != CreditLastDeflector && c.KillCredit != CreditSplit {
+		return fmt.Errorf("неизвестное значение kill-credit %q", c.KillCredit)
+	}
+	if c.LivesPerKill < 0 || c.MaxLives < 0 {
+		return fmt.Errorf("lives-per-kill и max-lives не могут быть отрицательными")
+	}
+	if c.BotReactionMin < 0 || c.BotReactionMin > c.BotReactionMax {
+		return fmt.Errorf("bot-reaction-min (%v) должен быть не меньше 0 и не больше bot-reaction-max (%v)", c.BotReactionMin, c.BotReactionMax)
+	}
+	if c.BotAimNoiseMin < 0 || c.BotAimNoiseMin > c.BotAimNoiseMax || c.BotAimNoiseMax > 1 {
+		return fmt.Errorf("разброс прицела ботов должен удовлетворять 0 <= min <= max <= 1, получено %v..%v", c.BotAimNoiseMin, c.BotAimNoiseMax)
+	}
+	if c.Bots < 0 || c.Bots > MaxBots {
+		return fmt.Errorf("bots должен быть от 0 до %d, получено %d", MaxBots, c.Bots)
+	}
+	if _, err := parseLogSampling(c.LogSample); err != nil {
+		return fmt.Errorf("log-sample: %w", err)
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log-level: %w", err)
+	}
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON {
+		return fmt.Errorf("неизвестное значение log-format %q", c.LogFormat)
+	}
+	if c.HighlightBuffer <= 0 || c.HighlightBuffer > time.Minute {
+		return fmt.Errorf("highlight-buffer должен быть от 0 до 1m, получено %v", c.HighlightBuffer)
+	}
+	if c.NoFireDuration < 0 || c.NoFireDuration > time.Minute {
+		return fmt.Errorf("no-fire должен быть от 0 до 1m, получено %v", c.NoFireDuration)
+	}
+	if c.MaxConnections < 0 {
+		return fmt.Errorf("max-connections не может быть отрицательным, получено %d", c.MaxConnections)
+	}
+	if !(c.MessageRate > 0) {
+		return fmt.Errorf("msg-rate должен быть больше 0, получено %v", c.MessageRate)
+	}
+	if c.MessageBurst < 1 {
+		return fmt.Errorf("msg-burst должен быть не меньше 1, получено %d", c.MessageBurst)
+	}
+	if c.PingInterval < 100*time.Millisecond || c.PingInterval > time.Minute {
+		return fmt.Errorf("ping-interval должен быть от 100ms до 1m, получено %v", c.PingInterval)
+	}
+	if c.PongTimeout <= c.PingInterval || c.PongTimeout > 5*time.Minute {
+		return fmt.Errorf("pong-timeout должен быть больше ping-interval (%v) и не больше 5m, получено %v", c.PingInterval, c.PongTimeout)
+	}
+	if c.SessionConflict != SessionTakeover && c.SessionConflict != SessionReject {
+		return fmt.Errorf("session-conflict должен быть %s или %s, получено %q", SessionTakeover, SessionReject, c.SessionConflict)
+	}
+	if c.DisconnectLinger < 0 || c.DisconnectLinger > time.Minute {
+		return fmt.Errorf("disconnect-linger должен быть от 0 до 1m, получено %v", c.DisconnectLinger)
+	}
+	if c.ReconnectGrace < 0 || c.ReconnectGrace > 10*time.Minute {
+		return fmt.Errorf("reconnect-grace должен быть от 0 до 10m, получено %v", c.ReconnectGrace)
+	}
+	if c.FloodKick < 0 {
+		return fmt.Errorf("flood-kick не может быть отрицательным, получено %v", c.FloodKick)
+	}
+	if c.MaxRooms < 0 {
+		return fmt.Errorf("max-rooms не может быть отрицательным, получено %d", c.MaxRooms)
+	}
+	if c.ProjectileLifetime < 0 {
+		return fmt.Errorf("projectile-lifetime не может быть отрицательным, получено %v", c.ProjectileLifetime)
+	}
+	if c.ProjectileRange < 0 || math.IsNaN(c.ProjectileRange) {
+		return fmt.Errorf("projectile-range не может быть отрицательным, получено %v", c.ProjectileRange)
+	}
+	if c.MaxProjectilesPerPlayer < 0 {
+		return fmt.Errorf("max-player-projectiles не может быть отрицательным, получено %d", c.MaxProjectilesPerPlayer)
+	}
+	if c.MaxProjectilesTotal < 0 {
+		return fmt.Errorf("max-projectiles не может быть отрицательным, получено %d", c.MaxProjectilesTotal)
+	}
+	if c.ShotQueue && (c.ShotQueueWindow <= 0 || c.ShotQueueWindow > time.Second*5) {
+		return fmt.Errorf("shot-queue-window должен быть от 1ms до 5s, получено %v", c.ShotQueueWindow)
+	}
+	if c.MaxPlayers < 0 {
+		return fmt.Errorf("max-players не может быть отрицательным, получено %d", c.MaxPlayers)
+	}
+	if c.ReadBufferSize < 128 || c.ReadBufferSize > 1<<20 {
+		return fmt.Errorf("read-buffer должен быть от 128 байт до 1 МБ, получено %d", c.ReadBufferSize)
+	}
+	if c.WriteBufferSize < 128 || c.WriteBufferSize > 1<<20 {
+		return fmt.Errorf("write-buffer должен быть от 128 байт до 1 МБ, получено %d", c.WriteBufferSize)
+	}
+	if c.CompressionLevel < MinCompressionLevel || c.CompressionLevel > MaxCompressionLevel {
+		return fmt.Errorf("compression-level должен быть от %d до %d, получено %d", MinCompressionLevel, MaxCompressionLevel, c.CompressionLevel)
+	}
+	if c.MaxStateMessageSize != 0 && c.MaxStateMessageSize < 1024 {
+		return fmt.Errorf("max-state-size должен быть 0 или не меньше 1024 байт, получено %d", c.MaxStateMessageSize)
+	}
+	if c.FullStateEvery < 1 {
+		return fmt.Errorf("full-state-every должен быть не меньше 1, получено %d", c.FullStateEvery)
+	}
+	if c.ReadLimit < 256 || c.ReadLimit > 1<<20 {
+		return fmt.Errorf("read-limit должен быть от 256 байт до 1 МБ, получено %d", c.ReadLimit)
+	}
+	return nil
+}
+
+// EnvPrefix - префикс переменных окружения с настройками: флаг -tick-rate задаётся и как TANKI_TICK_RATE
+const EnvPrefix = "TANKI_"
+
+// envName возвращает имя переменной окружения для флага
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv берёт значения флагов из переменных окружения. Вызывается до fs.Parse,
+// поэтому флаг командной строки важнее переменной окружения.
+func applyEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// applyConfig переносит проверенные настройки в описания стандартного танка и пушки
+func (c *Config) applyConfig() {
+	medium := tankClasses[DefaultTankClass]
+	medium.Speed = c.PlayerSpeed
+	medium.Lives = c.InitialLives
+	tankClasses[DefaultTankClass] = medium
+
+	cannon := weapons[DefaultWeapon]
+	cannon.FireRate = c.FireRate
+	weapons[DefaultWeapon] = cannon
+
+	trustedRelays, _ = parseRelayTrusted(c.RelayTrusted) // Уже проверено в validate
+}
+
+// secretFlags - флаги, значения которых не попадают в журнал
+var secretFlags = map[string]bool{"admin-token": true}
+
+// logConfig пишет в журнал действующие значения всех флагов (кроме секретов)
+func logConfig(fs *flag.FlagSet) {
+	var b strings.Builder
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretFlags[f.Name] && value != "" {
+			value = "***" // Секреты в журнал не пишем
+		}
+		fmt.Fprintf(&b, " -%s=%s", f.Name, value)
+	})
+	slog.Info("Настройки", "flags", strings.TrimSpace(b.String()))
+}
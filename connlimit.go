@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// --- Лимит соединений ---
+
+// Каждое соединение держит две горутины (reader и writer) и буферы WebSocket, поэтому число
+// одновременно обслуживаемых соединений ограничено семафором. Лимит считает все соединения,
+// а не только игроков. Сверх лимита соединение принимается только для того, чтобы закрыть его
+// с кодом 1013 (Try Again Later) и понятной причиной.
+
+const connRejectWriteTimeout = time.Second // Сколько ждать отправки кадра закрытия отклонённому клиенту
+
+// connSlots - семафор соединений (nil - без ограничения)
+var connSlots chan struct{}
+
+// initConnLimit создаёт семафор на limit соединений (0 - без ограничения)
+func initConnLimit(limit int) {
+	if limit > 0 {
+		connSlots = make(chan struct{}, limit)
+	}
+}
+
+// acquireConnSlot занимает место под соединение. Возвращает false, если мест нет.
+func acquireConnSlot() bool {
+	if connSlots != nil {
+		select {
+		case connSlots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	connectionsActive.Inc()
+	return true
+}
+
+// releaseConnSlot освобождает место, занятое acquireConnSlot
+func releaseConnSlot() {
+	connectionsActive.Dec()
+	if connSlots != nil {
+		<-connSlots
+	}
+}
+
+// rejectConnection закрывает WebSocket-соединение, для которого не нашлось места
+func rejectConnection(w http.ResponseWriter, r *http.Request) {
+	connectionsRejectedTotal.Inc()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("Ошибка обновления до WebSocket", "err", err)
+		return
+	}
+	defer conn.Close()
+	slog.Warn("Соединение отклонено: достигнут лимит соединений", "remote_addr", conn.RemoteAddr().String(), "limit", cap(connSlots))
+	msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "server is full, try again later")
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(connRejectWriteTimeout))
+}
+
+// Кроме общего лимита соединений, у каждой комнаты есть лимит игроков config.MaxPlayers.
+// Он считает танки людей, в том числе отключившихся, но ещё ждущих переподключения (их место
+// занято до истечения ReconnectGrace); ботов не считает. Переподключение с токеном сессии
+// лимит не проверяет - игрок уже занимает своё место. Зритель, вступающий в игру, занимает место
+// до того, как отпустит блокировку в ожидании своего writer (game.pendingJoins).
+
+// roomFull сообщает, достигнут ли в комнате лимит игроков. Вызывается под game.mutex.
+func (game *GameState) roomFull() bool {
+	if config.MaxPlayers <= 0 {
+		return false
+	}
+	humans := game.pendingJoins
+	for _, p := range game.Players {
+		if !p.Bot {
+			humans++
+		}
+	}
+	return humans >= config.MaxPlayers
+}
+
+// rejectFullRoom сообщает клиенту, что комната заполнена, и закрывает соединение
+func rejectFullRoom(conn *websocket.Conn) {
+	connectionsRejectedTotal.Inc()
+	deadline := time.Now().Add(connRejectWriteTimeout)
+	conn.SetWriteDeadline(deadline)
+	conn.WriteJSON(ServerMessage{Type: "error", Payload: ErrorPayload{Code: "server_full", Message: "server full"}})
+	msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "server full")
+	conn.WriteControl(websocket.CloseMessage, msg, deadline)
+	conn.Close()
+}
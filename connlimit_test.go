@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRoomFullIgnoresBots(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxPlayers = 2 })
+	game := newGameState(defaultMap())
+	game.Players["bot-1"] = &Player{ID: "bot-1", Bot: true}
+	game.Players["plr-1"] = &Player{ID: "plr-1"}
+	if game.roomFull() {
+		t.Fatal("бот занял место человека")
+	}
+	game.Players["plr-2"] = &Player{ID: "plr-2", Disconnected: true} // Ждёт переподключения
+	if !game.roomFull() {
+		t.Fatal("место игрока, ждущего переподключения, не учтено")
+	}
+}
+
+func TestMaxPlayersRejectsExtraClient(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxPlayers = 1 })
+	url := startTestServer(t)
+
+	first := dialTest(t, url)
+	_, token := assignedSession(t, first)
+
+	extra := dialTest(t, url)
+	msg := mustReadUntil(t, extra, "error")
+	var payload ErrorPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.Code != "server_full" {
+		t.Fatalf("ожидалась ошибка server_full, получено %s", msg.Payload)
+	}
+	_, err := readUntil(t, extra, "never")
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseTryAgainLater {
+		t.Fatalf("лишний клиент закрыт не с кодом %d: %v", websocket.CloseTryAgainLater, err)
+	}
+
+	// Переподключение с токеном лимит не проверяет: игрок уже занимает своё место
+	resumed := dialTest(t, url+"?token="+token)
+	mustReadUntil(t, resumed, "assignId")
+}
+
+func TestMaxConnectionsRejectsExtraConnection(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxConnections = 2 })
+	url := startTestServer(t)
+
+	first := dialTest(t, url)
+	mustReadUntil(t, first, "assignId")
+	spectator := dialTest(t, url+"?spectate=1") // Зрители тоже занимают соединение
+	mustReadUntil(t, spectator, "spectating")
+
+	extra := dialTest(t, url)
+	_, err := readUntil(t, extra, "never")
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseTryAgainLater {
+		t.Fatalf("лишнее соединение закрыто не с кодом %d: %v", websocket.CloseTryAgainLater, err)
+	}
+
+	// Ушедший клиент освобождает место
+	spectator.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(connSlots) == cap(connSlots) {
+		if time.Now().After(deadline) {
+			t.Fatal("место закрытого соединения не освободилось")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mustReadUntil(t, dialTest(t, url), "assignId")
+}
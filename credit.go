@@ -0,0 +1,54 @@
+package main
+
+// --- Владение снарядом и начисление очков ---
+
+// Политики начисления очков за попадание снаряда, который сменил владельца (отражение, пробитие)
+const (
+	CreditShooter       = "shooter"       // Всё получает тот, кто выпустил снаряд
+	CreditLastDeflector = "lastDeflector" // Всё получает последний владелец снаряда
+	CreditSplit         = "split"         // Очко и убийство получает каждый владелец из цепочки
+)
+
+// transferOwnership передаёт снаряд новому владельцу (например, отразившему его игроку).
+// Прежний владелец остаётся в цепочке владения. Вызывается под game.mutex.
+func transferOwnership(proj *Projectile, newOwnerID string) {
+	if newOwnerID == proj.OwnerID {
+		return
+	}
+	proj.OwnerChain = append(proj.OwnerChain, proj.OwnerID)
+	proj.OwnerID = newOwnerID
+}
+
+// originalOwner - игрок, выпустивший снаряд
+func originalOwner(proj *Projectile) string {
+	if len(proj.OwnerChain) > 0 {
+		return proj.OwnerChain[0]
+	}
+	return proj.OwnerID
+}
+
+// creditedOwners возвращает ID игроков, которым засчитывается попадание в victimID, согласно
+// config.KillCredit. Порядок детерминирован (по цепочке владения), каждый игрок встречается один раз,
+// пострадавший очков за попадание в себя не получает.
+func creditedOwners(proj *Projectile, victimID string) []string {
+	var candidates []string
+	switch config.KillCredit {
+	case CreditLastDeflector:
+		candidates = []string{proj.OwnerID}
+	case CreditSplit:
+		candidates = append(append([]string(nil), proj.OwnerChain...), proj.OwnerID)
+	default:
+		candidates = []string{originalOwner(proj)}
+	}
+
+	credited := make([]string, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	for _, id := range candidates {
+		if id == victimID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		credited = append(credited, id)
+	}
+	return credited
+}
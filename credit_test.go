@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// deflectedTwice - снаряд A, отражённый B, а затем C
+func deflectedTwice() *Projectile {
+	proj := &Projectile{ID: "prj-1", OwnerID: "A"}
+	transferOwnership(proj, "B")
+	transferOwnership(proj, "B") // Повторное отражение тем же игроком цепочку не удлиняет
+	transferOwnership(proj, "C")
+	return proj
+}
+
+func TestTransferOwnershipChain(t *testing.T) {
+	proj := deflectedTwice()
+	if proj.OwnerID != "C" || !slices.Equal(proj.OwnerChain, []string{"A", "B"}) {
+		t.Fatalf("владелец %s, цепочка %v; ожидались C и [A B]", proj.OwnerID, proj.OwnerChain)
+	}
+	if originalOwner(proj) != "A" {
+		t.Fatalf("стрелок %s, ожидался A", originalOwner(proj))
+	}
+}
+
+func TestCreditedOwners(t *testing.T) {
+	tests := []struct {
+		policy, victim string
+		want           []string
+	}{
+		{CreditShooter, "X", []string{"A"}},
+		{CreditLastDeflector, "X", []string{"C"}},
+		{CreditSplit, "X", []string{"A", "B", "C"}},
+		{CreditSplit, "B", []string{"A", "C"}}, // Пострадавший за попадание в себя не получает
+		{CreditShooter, "A", []string{}},       // Стрелок получил свой же отражённый снаряд
+	}
+	for _, tt := range tests {
+		withConfig(t, func(c *Config) { c.KillCredit = tt.policy })
+		if got := creditedOwners(deflectedTwice(), tt.victim); !slices.Equal(got, tt.want) {
+			t.Errorf("%s, пострадал %s: засчитано %v, ожидалось %v", tt.policy, tt.victim, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCreditScoresEveryOwner(t *testing.T) {
+	withConfig(t, func(c *Config) { c.KillCredit = CreditSplit })
+	game := newGameState(defaultMap())
+	for _, id := range []string{"A", "B", "C"} {
+		game.Players[id] = &Player{ID: id, Lives: 3, Radius: PlayerRadius}
+	}
+	victim := &Player{ID: "X", Lives: 1, Radius: PlayerRadius}
+	game.Players[victim.ID] = victim
+	proj := deflectedTwice()
+	proj.Damage = 1
+
+	game.applyProjectileHit(proj, victim)
+
+	for _, id := range []string{"A", "B", "C"} {
+		if p := game.Players[id]; p.Score != 1 || p.Kills != 1 {
+			t.Errorf("%s: очков %d, убийств %d; ожидалось 1 и 1", id, p.Score, p.Kills)
+		}
+	}
+	if game.Players["A"].ShotsHit != 1 || game.Players["C"].ShotsHit != 0 {
+		t.Error("точность должна засчитываться только стрелку")
+	}
+}
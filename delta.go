@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+)
+
+// --- Рассылка изменений состояния ---
+
+// При config.DeltaState клиент получает полное состояние (gameState) только при подключении,
+// раз в config.FullStateEvery рассылок и по запросу, а в остальное время - сообщение
+// "gameStateDelta" лишь с изменившимися игроками и снарядами:
+//
+//	seq      - номер этой рассылки
+//	base     - номер рассылки, к которой применяются изменения (последняя полученная клиентом)
+//	added    - новые объекты целиком
+//	updated  - изменившиеся объекты целиком (неизменившиеся не присылаются)
+//	removed  - ID исчезнувших объектов
+//
+// Полное состояние тоже содержит seq. Если base не совпадает с последним применённым номером
+// (сообщение потерялось при переполнении очереди), клиент отправляет действие "resync"
+// и получает полное состояние в следующей рассылке.
+
+// DeltaSection - изменения одного вида объектов
+type DeltaSection struct {
+	Added   []json.RawMessage `json:"added,omitempty"`
+	Updated []json.RawMessage `json:"updated,omitempty"`
+	Removed []string          `json:"removed,omitempty"`
+}
+
+// GameStateDeltaPayload - изменения состояния с рассылки base
+type GameStateDeltaPayload struct {
+	Seq           uint64       `json:"seq"`
+	Base          uint64       `json:"base"`
+	Players       DeltaSection `json:"players"`
+	Projectiles   DeltaSection `json:"projectiles"`
+	Phase         GamePhase    `json:"phase"`
+	PhaseEndsInMs int64        `json:"phaseEndsInMs"`
+	NoFireMs      int64        `json:"noFireMs"`
+	TeamScores    map[int]int  `json:"teamScores,omitempty"`
+	Events        []FeedEvent  `json:"events,omitempty"`
+	RadarBlips    []RadarBlip  `json:"radarBlips,omitempty"`
+	ServerTime    float64      `json:"serverTime"`
+	TickMs        float64      `json:"tickMs"`
+}
+
+// deltaTracker - что последним отправлено игроку. Меняется только рассылкой состояния
+// (и действием "resync" под полной блокировкой).
+type deltaTracker struct {
+	base        uint64            // Номер последней отправленной рассылки
+	players     map[string][]byte // Отправленный JSON игроков по ID
+	projectiles map[string][]byte // Отправленный JSON снарядов по ID
+	sinceFull   int               // Сколько изменений отправлено после последнего полного состояния
+	resync      bool              // Клиент просит полное состояние
+}
+
+// encodedEntities - объекты рассылки, сериализованные по одному разу для всех игроков
+type encodedEntities struct {
+	players     map[string][]byte
+	projectiles map[string][]byte
+}
+
+// encodeEntities сериализует игроков и снаряды по отдельности
+func encodeEntities(players []*Player, projectiles []*Projectile) (encodedEntities, error) {
+	enc := encodedEntities{
+		players:     make(map[string][]byte, len(players)),
+		projectiles: make(map[string][]byte, len(projectiles)),
+	}
+	for _, p := range players {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return enc, err
+		}
+		enc.players[p.ID] = data
+	}
+	for _, p := range projectiles {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return enc, err
+		}
+		enc.projectiles[p.ID] = data
+	}
+	return enc, nil
+}
+
+// needsFullState сообщает, нужно ли игроку полное состояние вместо изменений
+func needsFullState(player *Player) bool {
+	t := player.delta
+	return t == nil || t.resync || t.sinceFull >= config.FullStateEvery
+}
+
+// rememberFullState запоминает, что игроку отправлено полное состояние с этими объектами
+func (game *GameState) rememberFullState(player *Player, enc encodedEntities, players []*Player, projectiles []*Projectile) {
+	t := &deltaTracker{
+		base:        game.stateSeq,
+		players:     make(map[string][]byte, len(players)),
+		projectiles: make(map[string][]byte, len(projectiles)),
+	}
+	for _, p := range players {
+		t.players[p.ID] = enc.players[p.ID]
+	}
+	for _, p := range projectiles {
+		t.projectiles[p.ID] = enc.projectiles[p.ID]
+	}
+	player.delta = t
+}
+
+// sendStateDelta отправляет игроку изменения с его последней рассылки. projectiles - снаряды,
+// которые игрок должен видеть (все или ближайшие).
+func (game *GameState) sendStateDelta(player *Player, payload GameStatePayload, enc encodedEntities, projectiles []*Projectile) {
+	t := player.delta
+	delta := GameStateDeltaPayload{
+		Seq:           game.stateSeq,
+		Base:          t.base,
+		Phase:         payload.Phase,
+		PhaseEndsInMs: payload.PhaseEndsInMs,
+		NoFireMs:      payload.NoFireMs,
+		TeamScores:    payload.TeamScores,
+		Events:        payload.Events,
+		RadarBlips:    payload.RadarBlips,
+		ServerTime:    payload.ServerTime,
+		TickMs:        payload.TickMs,
+	}
+	playerIDs := make([]string, 0, len(payload.Players))
+	for _, p := range payload.Players {
+		playerIDs = append(playerIDs, p.ID)
+	}
+	delta.Players, t.players = diffEntities(t.players, enc.players, playerIDs)
+
+	current := make(map[string][]byte, len(projectiles))
+	projectileIDs := make([]string, 0, len(projectiles))
+	for _, p := range projectiles {
+		current[p.ID] = enc.projectiles[p.ID]
+		projectileIDs = append(projectileIDs, p.ID)
+	}
+	delta.Projectiles, t.projectiles = diffEntities(t.projectiles, current, projectileIDs)
+
+	msgBytes, err := json.Marshal(ServerMessage{Type: "gameStateDelta", Payload: delta})
+	if err != nil {
+		slog.Error("Ошибка маршалинга gameStateDelta", "err", err)
+		return
+	}
+	t.base = game.stateSeq
+	t.sinceFull++
+	queueMessage(player, msgBytes)
+}
+
+// diffEntities сравнивает отправленные объекты sent с текущими current (в порядке ids)
+// и возвращает изменения и новое отправленное состояние
+func diffEntities(sent, current map[string][]byte, ids []string) (DeltaSection, map[string][]byte) {
+	var section DeltaSection
+	for _, id := range ids {
+		data := current[id]
+		prev, ok := sent[id]
+		switch {
+		case !ok:
+			section.Added = append(section.Added, data)
+		case !bytes.Equal(prev, data):
+			section.Updated = append(section.Updated, data)
+		}
+	}
+	for id := range sent {
+		if _, ok := current[id]; !ok {
+			section.Removed = append(section.Removed, id)
+		}
+	}
+	return section, current
+}
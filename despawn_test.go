@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+// hitToDeath добивает victim снарядом shooter в новой игре и возвращает игру
+func hitToDeath(t *testing.T) *GameState {
+	t.Helper()
+	game := newGameState(defaultMap())
+	shooter := &Player{ID: "plr-shooter", Lives: 3, Radius: PlayerRadius}
+	victim := &Player{ID: "plr-victim", Lives: 1, Radius: PlayerRadius, X: 100, Y: 100}
+	game.Players[shooter.ID], game.Players[victim.ID] = shooter, victim
+	game.Projectiles["prj-victim"] = &Projectile{ID: "prj-victim", OwnerID: victim.ID}
+	game.applyProjectileHit(&Projectile{ID: "prj-shot", OwnerID: shooter.ID, Damage: 1, X: 100, Y: 100}, victim)
+	if !victim.Dead {
+		t.Fatal("снаряд не добил игрока с одной жизнью")
+	}
+	return game
+}
+
+func TestDespawnOnDeath(t *testing.T) {
+	withConfig(t, func(c *Config) { c.DespawnOnDeath = true })
+	if _, ok := hitToDeath(t).Projectiles["prj-victim"]; ok {
+		t.Fatal("снаряд погибшего игрока остался при -despawn-on-death")
+	}
+}
+
+func TestNoDespawnOnDeathByDefault(t *testing.T) {
+	withConfig(t, nil)
+	if _, ok := hitToDeath(t).Projectiles["prj-victim"]; !ok {
+		t.Fatal("снаряд погибшего игрока удалён без -despawn-on-death")
+	}
+}
+
+func TestNoDespawnOnDisconnect(t *testing.T) {
+	withConfig(t, func(c *Config) { c.DespawnOnDisconnect = false })
+	url := startTestServer(t)
+	conn := dialTest(t, url)
+	id, _ := assignedSession(t, conn)
+
+	game := rooms.defaultGame()
+	game.mutex.Lock()
+	game.Projectiles["prj-own"] = &Projectile{ID: "prj-own", OwnerID: id}
+	game.mutex.Unlock()
+
+	conn.Close()
+	waitFor(t, game, "отключение игрока", func() bool { return game.Players[id].MessageChan == nil })
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	if _, ok := game.Projectiles["prj-own"]; !ok {
+		t.Fatal("снаряд отключившегося игрока удалён при -despawn-on-disconnect=false")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// --- Сложность ботов ---
+
+// Сложность ботов задаётся одним уровнем botSkill от 0 (самые слабые) до 1 (самые сильные),
+// из которого получаются время реакции и разброс прицеливания в пределах, заданных в config.
+// В режиме config.AdaptiveBots gameLoop раз в DifficultyInterval смотрит на соотношение убийств
+// и смертей живых игроков и сдвигает уровень на DifficultyStep: доминирующим людям боты
+// становятся сильнее, проигрывающим - слабее.
+
+const (
+	DifficultyInterval = time.Second * 10 // Как часто пересчитывать сложность
+	DifficultyStep     = 0.1              // Насколько сдвигать уровень за раз
+	DifficultyHighKD   = 1.5              // K/D людей, при котором боты усиливаются
+	DifficultyLowKD    = 0.67             // K/D людей, при котором боты ослабевают
+	DefaultBotSkill    = 0.5              // Начальный уровень
+)
+
+// BotDifficulty - параметры поведения ботов
+type BotDifficulty struct {
+	ReactionTime time.Duration // Задержка между тем, как бот заметил цель, и реакцией на неё
+	AimNoise     float64       // Случайное отклонение прицела, радианы
+}
+
+// currentBotDifficulty переводит уровень botSkill в параметры ботов. Вызывается под game.mutex.
+func (game *GameState) currentBotDifficulty() BotDifficulty {
+	return BotDifficulty{
+		ReactionTime: config.BotReactionMax - time.Duration(game.botSkill*float64(config.BotReactionMax-config.BotReactionMin)),
+		AimNoise:     config.BotAimNoiseMax - game.botSkill*(config.BotAimNoiseMax-config.BotAimNoiseMin),
+	}
+}
+
+// adjustBotDifficulty сдвигает уровень ботов по K/D живых игроков
+func (game *GameState) adjustBotDifficulty() {
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	kills, deaths, humans := 0, 0, 0
+	for _, p := range game.Players {
+		if p.Bot || p.Disconnected {
+			continue
+		}
+		humans++
+		kills += p.Kills
+		deaths += p.Deaths
+	}
+	if humans == 0 {
+		return
+	}
+
+	// +1 сглаживает начало раунда, когда убийств и смертей ещё почти нет
+	kd := float64(kills+1) / float64(deaths+1)
+	previous := game.botSkill
+	switch {
+	case kd >= DifficultyHighKD:
+		game.botSkill = min(1, game.botSkill+DifficultyStep)
+	case kd <= DifficultyLowKD:
+		game.botSkill = max(0, game.botSkill-DifficultyStep)
+	}
+	if game.botSkill != previous {
+		d := game.currentBotDifficulty()
+		slog.Info("Сложность ботов изменена", "kd", kd, "from", previous, "to", game.botSkill,
+			"reaction", d.ReactionTime, "aim_noise", d.AimNoise)
+	}
+}
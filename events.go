@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+)
+
+// --- События симуляции ---
+
+// Игровая логика не рассылает сообщения и не пишет журнал боя сама: она копит события,
+// а updateGameLogic возвращает их вызывающему. gameLoop передаёт события в dispatchEvents,
+// которая уже вне полной блокировки отправляет сообщения клиентам и пишет журнал.
+// События, возникшие между тиками (например, появление нового игрока), попадают в ближайший тик.
+
+// EventKind - тип игрового события
+type EventKind string
+
+const (
+	EventShot              EventKind = "shot"              // Игрок выстрелил
+	EventShotRejected      EventKind = "shotRejected"      // Выстрел отклонён (Detail - причина)
+	EventHit               EventKind = "hit"               // Снаряд попал в игрока
+	EventKill              EventKind = "kill"              // Игрок погиб: попадание добило его или (Detail DeathPit) он упал в обрыв
+	EventSpawn             EventKind = "spawn"             // Игрок появился на арене
+	EventPlayerRemoved     EventKind = "playerRemoved"     // Отключившийся игрок окончательно убран
+	EventProjectileRemoved EventKind = "projectileRemoved" // Снаряд исчез (попадание, стена, край арены)
+	EventWallDestroyed     EventKind = "wallDestroyed"     // Разрушаемая стена уничтожена
+	EventWallsChanged      EventKind = "wallsChanged"      // Набор стен изменился
+	EventRoundOver         EventKind = "roundOver"         // Раунд окончен (PlayerID - победитель, пусто при ничьей; Detail - причина)
+	EventRoundStart        EventKind = "roundStart"        // Начался новый раунд
+	EventTeamSwitched      EventKind = "teamSwitched"      // Игрок переведён в другую команду
+	EventExplosion         EventKind = "explosion"         // Снаряд взорвался
+	EventMapChanged        EventKind = "mapChanged"        // Сменилась карта (Detail - название)
+	EventSound             EventKind = "sound"             // Звук в точке арены (Detail - тип звучащего события)
+)
+
+// GameEvent - событие, произошедшее за тик. Сообщение клиентам сериализуется в момент события,
+// пока действует блокировка, поэтому событие можно обрабатывать и после её снятия.
+type GameEvent struct {
+	Kind         EventKind `json:"kind"`
+	PlayerID     string    `json:"playerId,omitempty"`     // Кто вызвал событие (стрелок, появившийся игрок, победитель)
+	TargetID     string    `json:"targetId,omitempty"`     // На кого оно направлено (пострадавший)
+	ProjectileID string    `json:"projectileId,omitempty"` // Снаряд, если он участвует
+	Detail       string    `json:"detail,omitempty"`       // Уточнение (причина отказа, ID стены)
+	X            float64   `json:"x,omitempty"`            // Где на арене произошло событие (для выстрелов, попаданий, взрывов)
+	Y            float64   `json:"y,omitempty"`
+
+	to      string // Адресат сообщения (пусто - все игроки)
+	message []byte // Сообщение клиентам (nil - событие только для журнала)
+	audible bool   // Сообщение получают только игроки в пределах config.Earshot от X, Y
+}
+
+// emit добавляет событие в очередь текущего тика. Вызывается под game.mutex.
+func (game *GameState) emit(ev GameEvent) {
+	game.events = append(game.events, ev)
+	game.recordFeed(ev)
+}
+
+// emitMessage добавляет событие вместе с сообщением для игрока to (пусто - для всех). Вызывается под game.mutex.
+func (game *GameState) emitMessage(ev GameEvent, to string, msg ServerMessage) {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Ошибка маршалинга сообщения", "type", msg.Type, "err", err)
+	} else {
+		ev.to, ev.message = to, msgBytes
+	}
+	game.emit(ev)
+}
+
+// takeEvents забирает накопленные события. Вызывается под game.mutex.
+func (game *GameState) takeEvents() []GameEvent {
+	events := game.events
+	game.events = nil
+	return events
+}
+
+// dispatchEvents пишет события в журнал и рассылает их сообщения клиентам
+func (game *GameState) dispatchEvents(events []GameEvent) {
+	if len(events) == 0 {
+		return
+	}
+	for _, ev := range events {
+		game.logEvent(ev)
+		if game.highlights != nil {
+			game.highlights.observe(ev)
+		}
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	for _, ev := range events {
+		if ev.message == nil {
+			continue
+		}
+		if ev.to == "" {
+			for _, player := range game.Players {
+				if ev.audible && !game.inEarshot(player, ev.X, ev.Y) {
+					continue
+				}
+				queueMessage(player, ev.message)
+			}
+			if !ev.audible {
+				game.queueSpectators(ev.message) // Звуки привязаны к месту, а у зрителя его нет
+			}
+			continue
+		}
+		if player, ok := game.Players[ev.to]; ok {
+			queueMessage(player, ev.message)
+		}
+	}
+}
+
+// --- Звуки ---
+
+// SoundPayload - звучащее событие с позицией на арене. Громкость по расстоянию до источника
+// рассчитывает клиент; при заданном config.Earshot сервер не отправляет звуки дальше этого расстояния.
+type SoundPayload struct {
+	Kind         EventKind `json:"kind"` // EventShot, EventHit или EventExplosion
+	X            float64   `json:"x"`
+	Y            float64   `json:"y"`
+	PlayerID     string    `json:"playerId,omitempty"`
+	ProjectileID string    `json:"projectileId,omitempty"`
+}
+
+// emitSound рассылает звук события ev в его позиции. Вызывается под game.mutex.
+func (game *GameState) emitSound(ev GameEvent) {
+	game.emitMessage(GameEvent{Kind: EventSound, Detail: string(ev.Kind), X: ev.X, Y: ev.Y, audible: true}, "",
+		ServerMessage{Type: "sound", Payload: SoundPayload{
+			Kind:         ev.Kind,
+			X:            ev.X,
+			Y:            ev.Y,
+			PlayerID:     ev.PlayerID,
+			ProjectileID: ev.ProjectileID,
+		}})
+}
+
+// inEarshot сообщает, слышит ли игрок звук в точке (x, y). Погибший слышит то же, что и тот, за кем наблюдает.
+// Вызывается под game.mutex (хотя бы на чтение).
+func (game *GameState) inEarshot(player *Player, x, y float64) bool {
+	if config.Earshot <= 0 {
+		return true
+	}
+	listener := game.observer(player)
+	return math.Hypot(listener.X-x, listener.Y-y) <= config.Earshot
+}
+
+// logEvent пишет в журнал боевые события (частые - выборочно, см. logsampling.go)
+func (game *GameState) logEvent(ev GameEvent) {
+	if !game.sampleLog(ev.Kind) {
+		return
+	}
+	switch ev.Kind {
+	case EventShot:
+		slog.Debug("Выстрел", "event", ev.Kind, "player_id", ev.PlayerID, "projectile_id", ev.ProjectileID)
+	case EventShotRejected:
+		slog.Debug("Выстрел отклонён", "event", ev.Kind, "player_id", ev.PlayerID, "reason", ev.Detail)
+	case EventHit:
+		slog.Debug("Попадание", "event", ev.Kind, "player_id", ev.PlayerID, "projectile_id", ev.ProjectileID, "target_id", ev.TargetID)
+	case EventKill:
+		if ev.Detail == DeathPit {
+			slog.Info("Игрок упал в обрыв", "event", ev.Kind, "target_id", ev.TargetID)
+			break
+		}
+		slog.Info("Игрок уничтожен", "event", ev.Kind, "player_id", ev.PlayerID, "target_id", ev.TargetID)
+	case EventSpawn:
+		slog.Info("Игрок появился на арене", "event", ev.Kind, "player_id", ev.PlayerID)
+	case EventExplosion:
+		slog.Debug("Взрыв снаряда", "event", ev.Kind, "player_id", ev.PlayerID, "projectile_id", ev.ProjectileID)
+	case EventWallDestroyed:
+		slog.Info("Стена разрушена", "event", ev.Kind, "wall_id", ev.Detail, "projectile_id", ev.ProjectileID)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+)
+
+// --- Взрывы ---
+
+// Снаряд с ExplosionRadius > 0 взрывается при попадании в стену или танк и наносит урон всем
+// танкам в радиусе. При включённых цепных взрывах (config.ChainExplosions) взрыв подрывает
+// попавшие в радиус взрывоопасные снаряды, а те - следующие. Цепочка обрабатывается очередью
+// в пределах тика, но не длиннее MaxChainExplosions: оставшиеся снаряды взорвутся в следующих тиках.
+//
+// Подорванный чужим взрывом снаряд переходит к владельцу взорвавшегося (transferOwnership),
+// поэтому очки за жертв цепочки начисляются по общей политике config.KillCredit:
+// shooter - хозяину подорванного снаряда, lastDeflector - тому, кто начал цепочку, split - обоим.
+
+const MaxChainExplosions = 32 // Сколько взрывов может произойти в одной цепочке за тик
+
+// ExplosionPayload - сообщение о взрыве (рассылается всем)
+type ExplosionPayload struct {
+	ProjectileID string  `json:"projectileId"`
+	OwnerID      string  `json:"ownerId"`
+	X            float64 `json:"x"`
+	Y            float64 `json:"y"`
+	Radius       float64 `json:"radius"`
+	Chain        int     `json:"chain"` // Глубина в цепочке (0 - взрыв от попадания)
+}
+
+// explosive сообщает, взрывается ли снаряд
+func (p *Projectile) explosive() bool {
+	return p.ExplosionRadius > 0
+}
+
+// detonate взрывает снаряд first и, если включены цепные взрывы, задетые им взрывоопасные снаряды.
+// Возвращает ID взорвавшихся снарядов - их должен удалить вызывающий. Вызывается под game.mutex.
+func (game *GameState) detonate(first *Projectile) []string {
+	type pending struct {
+		proj  *Projectile
+		chain int
+	}
+	first.Exploded = true
+	queue := []pending{{first, 0}}
+	var detonated []string
+
+	for len(queue) > 0 {
+		if len(detonated) >= MaxChainExplosions {
+			// Цепочка слишком длинная - остаток взорвётся, когда до него дойдёт следующий тик
+			for _, rest := range queue {
+				rest.proj.Exploded = false
+			}
+			slog.Warn("Цепочка взрывов прервана", "projectile_id", first.ID, "explosions", len(detonated))
+			break
+		}
+		current := queue[0]
+		queue = queue[1:]
+		proj := current.proj
+		detonated = append(detonated, proj.ID)
+
+		explosion := GameEvent{Kind: EventExplosion, PlayerID: proj.OwnerID, ProjectileID: proj.ID, X: proj.X, Y: proj.Y}
+		game.emitMessage(explosion, "",
+			ServerMessage{Type: "explosion", Payload: ExplosionPayload{
+				ProjectileID: proj.ID,
+				OwnerID:      proj.OwnerID,
+				X:            proj.X,
+				Y:            proj.Y,
+				Radius:       proj.ExplosionRadius,
+				Chain:        current.chain,
+			}})
+		game.emitSound(explosion)
+
+		for _, player := range game.Players {
+			if game.canHit(proj, player) && math.Hypot(player.X-proj.X, player.Y-proj.Y) < proj.ExplosionRadius+player.Radius {
+				game.applyProjectileHit(proj, player)
+			}
+		}
+
+		if !config.ChainExplosions {
+			continue
+		}
+		for _, other := range game.Projectiles {
+			if other.Exploded || !other.explosive() {
+				continue
+			}
+			if math.Hypot(other.X-proj.X, other.Y-proj.Y) <= proj.ExplosionRadius+other.CollisionRadius {
+				other.Exploded = true
+				transferOwnership(other, proj.OwnerID)
+				queue = append(queue, pending{other, current.chain + 1})
+			}
+		}
+	}
+	return detonated
+}
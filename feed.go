@@ -0,0 +1,57 @@
+package main
+
+// --- Лента событий ---
+
+// Выстрелы, попадания и гибели попадают в ленту событий, которая уходит клиентам в поле "events"
+// ближайшей рассылки состояния (gameState, gameStateDelta или первая часть gameStateChunk) и
+// очищается. Каждое событие отправляется один раз; клиент, которому состояние приходит реже
+// (параметр rate), видит только события доставшихся ему рассылок. Ники записываются в момент
+// события, поэтому лента верна, даже если игрок успел сменить ник или уйти.
+
+const MaxFeedEvents = 64 // Сколько событий помещается в одну рассылку (лишние отбрасываются)
+
+// FeedEvent - событие ленты
+type FeedEvent struct {
+	Kind       EventKind `json:"kind"`                 // EventShot, EventHit или EventKill
+	PlayerID   string    `json:"playerId,omitempty"`   // Стрелок, попавший или убийца
+	PlayerName string    `json:"playerName,omitempty"` // Его ник
+	TargetID   string    `json:"targetId,omitempty"`   // Пострадавший или погибший
+	TargetName string    `json:"targetName,omitempty"`
+	Detail     string    `json:"detail,omitempty"` // Для гибели - причина (DeathPit)
+}
+
+// recordFeed добавляет событие ev в ленту, если оно для неё. Вызывается из emit под game.mutex.
+func (game *GameState) recordFeed(ev GameEvent) {
+	switch ev.Kind {
+	case EventShot, EventHit, EventKill:
+	default:
+		return
+	}
+	if len(game.feed) >= MaxFeedEvents {
+		return
+	}
+	game.feed = append(game.feed, FeedEvent{
+		Kind:       ev.Kind,
+		PlayerID:   ev.PlayerID,
+		PlayerName: game.nicknameOf(ev.PlayerID),
+		TargetID:   ev.TargetID,
+		TargetName: game.nicknameOf(ev.TargetID),
+		Detail:     ev.Detail,
+	})
+}
+
+// nicknameOf возвращает ник игрока или "", если его нет. Вызывается под game.mutex.
+func (game *GameState) nicknameOf(playerID string) string {
+	if p, ok := game.Players[playerID]; ok {
+		return p.Nickname
+	}
+	return ""
+}
+
+// takeFeed забирает ленту для рассылки. Вызывается рассылкой под game.mutex.RLock: кроме неё
+// ленту меняет только emit под полной блокировкой, поэтому запись безопасна.
+func (game *GameState) takeFeed() []FeedEvent {
+	feed := game.feed
+	game.feed = nil
+	return feed
+}
@@ -0,0 +1,86 @@
+package main
+
+import "time"
+
+// --- Готовность к выстрелу ---
+
+// Все ограничения стрельбы (разминка, перезарядка, лимиты снарядов) проверяются в одном месте -
+// fireBlock. По её результату игровой цикл решает, стрелять ли, а клиент получает в Player.CanFireAt
+// единый момент, когда танк сможет выстрелить.
+
+// FireCooldown - выстрел ждёт окончания перезарядки (это не отказ: выстрел произойдёт сам)
+const FireCooldown = "cooldown"
+
+// ShootResultPayload - ответ стрелявшему на каждое действие "shoot". Success означает, что пушка
+// перезаряжена и выстрел произойдёт в ближайшем тике; иначе выстрел ждёт конца перезарядки,
+// а CooldownMs - сколько осталось ждать. Прочие помехи (разминка, лимиты снарядов) по-прежнему
+// приходят в "shotRejected".
+type ShootResultPayload struct {
+	Success    bool   `json:"success"`
+	CooldownMs int64  `json:"cooldownMs"`    // Остаток перезарядки, мс по настоящим часам (0 при Success)
+	Seq        uint32 `json:"seq,omitempty"` // Номер выстрела из ShootCommand
+}
+
+// CanFireUnknown - значение Player.CanFireAt, когда момент выстрела заранее неизвестен:
+// танк погиб, идёт перерыв или нужно дождаться, пока исчезнет один из снарядов
+const CanFireUnknown = -1
+
+// fireBlock возвращает, что мешает игроку выстрелить прямо сейчас ("" - ничего), и игровой момент,
+// когда помеха исчезнет (нулевое время - неизвестно). Если помех несколько, причина - самая
+// приоритетная (разминка, затем перезарядка, затем лимит снарядов), а момент - самый поздний.
+// Вызывается под game.mutex.
+func (game *GameState) fireBlock(p *Player) (string, time.Time) {
+	now := game.gameNow()
+	reason, until := "", now
+	if noFire := game.noFireUntil(p); now.Before(noFire) {
+		reason, until = ShotRejectNoFire, noFire
+	}
+	if ready := p.LastShotTime.Add(weapons[p.Weapon].Cooldown()); now.Before(ready) {
+		if reason == "" {
+			reason = FireCooldown
+		}
+		if ready.After(until) {
+			until = ready
+		}
+	}
+	if reason != "" {
+		return reason, until
+	}
+	if limit := game.projectileLimitReason(p); limit != "" {
+		return limit, time.Time{}
+	}
+	return "", now
+}
+
+// canFireAt переводит игровой момент until в миллисекунды Unix по настоящим часам
+// (0 - можно стрелять сейчас, CanFireUnknown - неизвестно). Вызывается под game.mutex.
+func (game *GameState) canFireAt(reason string, until time.Time) int64 {
+	if reason == "" {
+		return 0
+	}
+	if until.IsZero() {
+		return CanFireUnknown
+	}
+	wait := time.Duration(float64(until.Sub(game.gameNow())) / game.TimeScale)
+	return time.Now().Add(wait).UnixMilli()
+}
+
+// cooldownRemaining возвращает, сколько по настоящим часам осталось до конца перезарядки
+// текущего оружия игрока (0 - пушка готова). Вызывается под game.mutex.
+func (game *GameState) cooldownRemaining(p *Player) time.Duration {
+	left := weapons[p.Weapon].Cooldown() - game.gameNow().Sub(p.LastShotTime)
+	if left <= 0 {
+		return 0
+	}
+	return time.Duration(float64(left) / game.TimeScale)
+}
+
+// sendShootResult отвечает игроку на действие "shoot". Вызывается под game.mutex.
+func (game *GameState) sendShootResult(p *Player) {
+	left := game.cooldownRemaining(p)
+	sendToPlayer(p, ServerMessage{Type: "shootResult", Payload: ShootResultPayload{
+		Success:    left == 0,
+		CooldownMs: left.Milliseconds(),
+		Seq:        p.ShotSeq,
+	}})
+}
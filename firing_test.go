@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// firingTestGame - игра с готовым к выстрелу вправо игроком в (200, 200)
+func firingTestGame(t *testing.T) (*GameState, *Player) {
+	t.Helper()
+	game := newGameState(defaultMap())
+	game.Phase = PhasePlaying
+	game.NoFireUntil = time.Time{}
+	shooter := &Player{ID: "plr-shooter", X: 200, Y: 200, Lives: 3, Radius: PlayerRadius, Layer: LayerPlayer, Weapon: DefaultWeapon, Class: DefaultTankClass}
+	game.Players[shooter.ID] = shooter
+	return game, shooter
+}
+
+// addTarget ставит противника с тремя жизнями в точку (x, y)
+func addTarget(game *GameState, id string, x, y float64) *Player {
+	p := &Player{ID: id, X: x, Y: y, Lives: 3, Radius: PlayerRadius, Layer: LayerPlayer, Weapon: DefaultWeapon, Class: DefaultTankClass}
+	game.Players[id] = p
+	return p
+}
+
+func TestSegmentPointDistance(t *testing.T) {
+	tests := []struct {
+		px, py, dist, t float64
+	}{
+		{5, 3, 3, 0.5}, // Над серединой отрезка (0,0)-(10,0)
+		{-4, 3, 5, 0},  // За началом
+		{13, -4, 5, 1}, // За концом
+		{10, 0, 0, 1},  // В конце
+	}
+	for _, tt := range tests {
+		dist, at := segmentPointDistance(0, 0, 10, 0, tt.px, tt.py)
+		if math.Abs(dist-tt.dist) > 1e-9 || math.Abs(at-tt.t) > 1e-9 {
+			t.Errorf("точка (%v, %v): расстояние %v, t %v; ожидалось %v, %v", tt.px, tt.py, dist, at, tt.dist, tt.t)
+		}
+	}
+}
+
+func TestBarrelSweepHitsNearestToShooter(t *testing.T) {
+	withConfig(t, nil)
+	game, shooter := firingTestGame(t)
+	far := addTarget(game, "plr-far", 200+BarrelLength, 200)
+	near := addTarget(game, "plr-near", 200+BarrelLength/2, 210)
+	proj := &Projectile{OwnerID: shooter.ID, X: 200 + BarrelLength, Y: 200, Mask: DefaultProjectileMask, CollisionRadius: ProjectileRadius}
+	if hit := game.barrelSweepHit(shooter, proj); hit != near {
+		t.Fatalf("попадание в %v, ожидалось в ближайшего к стрелку %s (дальний - %s)", hit, near.ID, far.ID)
+	}
+	delete(game.Players, near.ID)
+	delete(game.Players, far.ID)
+	addTarget(game, "plr-away", 400, 200)
+	if hit := game.barrelSweepHit(shooter, proj); hit != nil {
+		t.Fatalf("попадание в далёкого игрока %s при проверке ствола", hit.ID)
+	}
+}
+
+func TestShotSpawnsAtBarrelTip(t *testing.T) {
+	withConfig(t, nil)
+	game, shooter := firingTestGame(t)
+	shooter.WantsToShoot = true
+	game.updateGameLogic(0, time.Now())
+	if len(game.Projectiles) != 1 {
+		t.Fatalf("снарядов %d, ожидался 1", len(game.Projectiles))
+	}
+	for _, proj := range game.Projectiles {
+		if math.Abs(proj.X-(200+BarrelLength)) > 1e-9 || math.Abs(proj.Y-200) > 1e-9 {
+			t.Fatalf("снаряд появился в (%v, %v), ожидалось дуло (%v, 200)", proj.X, proj.Y, 200+BarrelLength)
+		}
+	}
+}
+
+// Противник вплотную к стволу получает попадание сразу, снаряд на арене не появляется
+func TestPointBlankShotHits(t *testing.T) {
+	withConfig(t, nil)
+	game, shooter := firingTestGame(t)
+	target := addTarget(game, "plr-target", 200+BarrelLength, 200)
+	shooter.WantsToShoot = true
+	game.updateGameLogic(0, time.Now())
+	if target.Lives != 2 {
+		t.Fatalf("у цели %d жизней, ожидалось 2", target.Lives)
+	}
+	if len(game.Projectiles) != 0 {
+		t.Fatalf("после выстрела в упор на арене %d снарядов", len(game.Projectiles))
+	}
+}
+
+func TestProjectileLimits(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxProjectilesPerPlayer, c.MaxProjectilesTotal = 4, 6 })
+	game, shooter := firingTestGame(t)
+	shooter.Weapon = "rocket" // Не больше weapons["rocket"].MaxActive в полёте
+	launch := func(id, owner, weapon string) {
+		game.Projectiles[id] = &Projectile{ID: id, OwnerID: owner, Weapon: weapon}
+	}
+
+	for i := 0; i < weapons["rocket"].MaxActive; i++ {
+		if reason := game.projectileLimitReason(shooter); reason != "" {
+			t.Fatalf("выстрел %d отклонён: %s", i+1, reason)
+		}
+		launch(fmt.Sprintf("rocket-%d", i), shooter.ID, "rocket")
+	}
+	if reason := game.projectileLimitReason(shooter); reason != ShotRejectWeaponLimit {
+		t.Fatalf("причина %q, ожидался лимит оружия", reason)
+	}
+
+	// Ракеты в полёте не мешают стрелять из пушки - до общего лимита игрока
+	shooter.Weapon = DefaultWeapon
+	for i := 0; len(game.Projectiles) < config.MaxProjectilesPerPlayer; i++ {
+		launch(fmt.Sprintf("shell-%d", i), shooter.ID, DefaultWeapon)
+	}
+	if reason := game.projectileLimitReason(shooter); reason != ShotRejectPlayerLimit {
+		t.Fatalf("причина %q, ожидался лимит игрока", reason)
+	}
+
+	// Общий лимит арены действует на всех
+	other := addTarget(game, "plr-other", 600, 600)
+	if reason := game.projectileLimitReason(other); reason != "" {
+		t.Fatalf("другому игроку отказано: %s", reason)
+	}
+	launch("other-1", other.ID, DefaultWeapon)
+	launch("other-2", other.ID, DefaultWeapon)
+	if reason := game.projectileLimitReason(other); reason != ShotRejectGlobalLimit {
+		t.Fatalf("причина %q, ожидался общий лимит", reason)
+	}
+}
+
+// Попадание считается по радиусу попадания оружия, а не по (большему) радиусу отрисовки
+func TestVisualRadiusDoesNotHit(t *testing.T) {
+	withConfig(t, nil)
+	weapons["flare"] = WeaponDef{Name: "flare", CollisionRadius: 2, VisualRadius: 20}
+	t.Cleanup(func() { delete(weapons, "flare") })
+
+	for _, tt := range []struct {
+		offset float64 // Расстояние от линии выстрела до центра цели
+		hit    bool
+	}{
+		{PlayerRadius + 10, false}, // Внутри радиуса отрисовки (15+20), но дальше радиуса попадания (15+2)
+		{PlayerRadius, true},
+	} {
+		game, shooter := firingTestGame(t)
+		shooter.Weapon = "flare"
+		target := addTarget(game, "plr-target", 200+BarrelLength, 200+tt.offset)
+		shooter.WantsToShoot = true
+		game.updateGameLogic(0, time.Now()) // Выстрел: снаряд у дула, рядом с целью
+		game.updateGameLogic(0, time.Now()) // Проверка столкновений на месте
+
+		for _, proj := range game.Projectiles {
+			if proj.CollisionRadius != 2 || proj.VisualRadius != 20 {
+				t.Fatalf("радиусы снаряда %v/%v, ожидались радиусы оружия 2/20", proj.CollisionRadius, proj.VisualRadius)
+			}
+		}
+		if hit := target.Lives < 3; hit != tt.hit {
+			t.Errorf("цель в %v px от линии выстрела: попадание = %v, ожидалось %v", tt.offset, hit, tt.hit)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// --- Проверки живости и готовности ---
+
+// Для оркестратора (Kubernetes и т.п.):
+//
+//	GET /healthz - 200, пока HTTP-сервер отвечает (живость: не отвечает - перезапустить)
+//	GET /readyz  - 200, когда игровой цикл и рассылка сделали первый тик, иначе 503
+//	               (готовность: до этого трафик на сервер не направляется)
+//
+// Обе ручки не берут блокировок игры, поэтому отвечают сразу даже под нагрузкой.
+// Ретранслятор своей игры не ведёт и готов сразу.
+
+// Первый тик игрового цикла и рассылки (в любой комнате; первой запускается комната по умолчанию)
+var (
+	gameLoopStarted      atomic.Bool
+	broadcastLoopStarted atomic.Bool
+)
+
+// handleHealthz - GET /healthz
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz - GET /readyz
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if config.RelayUpstream == "" && !(gameLoopStarted.Load() && broadcastLoopStarted.Load()) {
+		http.Error(w, "игровые циклы ещё не запущены", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// --- Запись ярких моментов ---
+
+// Рекордер держит в кольцевом буфере последние рассылки состояния игры. Когда происходит заметное
+// событие (мульти-убийство или победа в раунде), он дожидается ещё HighlightTail и сбрасывает
+// в файл последние config.HighlightBuffer + HighlightTail состояний. Пока запись ожидает сброса,
+// новые события её не продлевают и отдельной записи не создают.
+
+const (
+	HighlightTail        = time.Second * 3 // Сколько записывать после события
+	MultiKillCount       = 3               // Убийств одного игрока для мульти-убийства...
+	MultiKillWindow      = time.Second * 5 // ...за это время
+	highlightFilePerm    = 0o644
+	highlightDirPerm     = 0o755
+	highlightReasonRound = "roundWin"
+	highlightReasonMulti = "multiKill"
+)
+
+// highlightFrame - одна рассылка состояния игры
+type highlightFrame struct {
+	TimeMs int64           `json:"timeMs"` // Время рассылки, мс Unix
+	State  json.RawMessage `json:"state"`  // Сообщение gameState как есть
+}
+
+// highlightFile - содержимое файла с ярким моментом
+type highlightFile struct {
+	Reason      string           `json:"reason"`
+	PlayerID    string           `json:"playerId"`
+	TriggeredAt int64            `json:"triggeredAt"` // Время события, мс Unix
+	Frames      []highlightFrame `json:"frames"`
+}
+
+// HighlightRecorder - кольцевой буфер последних состояний и ожидающая сброса запись
+type HighlightRecorder struct {
+	mu     sync.Mutex
+	dir    string
+	window time.Duration // Сколько хранить до события
+
+	frames []highlightFrame // Кольцевой буфер
+	next   int              // Куда писать следующий кадр
+	full   bool             // Буфер заполнен хотя бы раз
+
+	pending     *highlightFile         // Запись, ожидающая сброса (nil - нет)
+	flushAt     time.Time              // Когда сбросить ожидающую запись
+	recentKills map[string][]time.Time // Недавние убийства по игрокам (для мульти-убийств)
+}
+
+// newHighlightRecorder создаёт рекордер, пишущий файлы в dir и хранящий window состояний до события
+func newHighlightRecorder(dir string, window time.Duration) (*HighlightRecorder, error) {
+	if err := os.MkdirAll(dir, highlightDirPerm); err != nil {
+		return nil, err
+	}
+	capacity := int((window+HighlightTail).Seconds()*float64(config.BroadcastRate)) + 1
+	return &HighlightRecorder{
+		dir:         dir,
+		window:      window,
+		frames:      make([]highlightFrame, capacity),
+		recentKills: make(map[string][]time.Time),
+	}, nil
+}
+
+// record добавляет рассылку состояния в буфер и сбрасывает ожидающую запись, если пришло время.
+// msgBytes после вызова не должен изменяться.
+func (r *HighlightRecorder) record(msgBytes []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.frames[r.next] = highlightFrame{TimeMs: now.UnixMilli(), State: msgBytes}
+	r.next = (r.next + 1) % len(r.frames)
+	if r.next == 0 {
+		r.full = true
+	}
+
+	if r.pending != nil && !now.Before(r.flushAt) {
+		h := r.pending
+		r.pending = nil
+		h.Frames = r.framesSince(h.TriggeredAt - r.window.Milliseconds())
+		go r.write(h) // Запись на диск не должна задерживать рассылку
+	}
+}
+
+// framesSince возвращает кадры буфера не старше sinceMs в хронологическом порядке. Вызывается под r.mu.
+func (r *HighlightRecorder) framesSince(sinceMs int64) []highlightFrame {
+	var ordered []highlightFrame
+	if r.full {
+		ordered = append(ordered, r.frames[r.next:]...)
+	}
+	ordered = append(ordered, r.frames[:r.next]...)
+
+	result := make([]highlightFrame, 0, len(ordered))
+	for _, f := range ordered {
+		if f.TimeMs >= sinceMs {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// observe проверяет, является ли событие заметным, и при необходимости запускает запись
+func (r *HighlightRecorder) observe(ev GameEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	switch ev.Kind {
+	case EventRoundOver:
+		r.trigger(highlightReasonRound, ev.PlayerID, now)
+	case EventKill:
+		if ev.PlayerID == "" {
+			return // Гибель без убийцы (например, в обрыве)
+		}
+		kills := append(r.recentKills[ev.PlayerID], now)
+		for len(kills) > 0 && now.Sub(kills[0]) > MultiKillWindow {
+			kills = kills[1:]
+		}
+		r.recentKills[ev.PlayerID] = kills
+		if len(kills) >= MultiKillCount {
+			delete(r.recentKills, ev.PlayerID) // Следующее мульти-убийство считается заново
+			r.trigger(highlightReasonMulti, ev.PlayerID, now)
+		}
+	case EventPlayerRemoved:
+		delete(r.recentKills, ev.PlayerID)
+	}
+}
+
+// trigger ставит запись в очередь на сброс через HighlightTail. Вызывается под r.mu.
+func (r *HighlightRecorder) trigger(reason, playerID string, at time.Time) {
+	if r.pending != nil {
+		return
+	}
+	r.pending = &highlightFile{Reason: reason, PlayerID: playerID, TriggeredAt: at.UnixMilli()}
+	r.flushAt = at.Add(HighlightTail)
+	slog.Info("Яркий момент, запись будет сохранена позже", "reason", reason, "player_id", playerID, "after", HighlightTail)
+}
+
+// write сохраняет запись в файл
+func (r *HighlightRecorder) write(h *highlightFile) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		slog.Error("Ошибка маршалинга яркого момента", "err", err)
+		return
+	}
+	name := fmt.Sprintf("highlight-%d-%s-%s.json", h.TriggeredAt, h.Reason, h.PlayerID)
+	path := filepath.Join(r.dir, name)
+	if err := os.WriteFile(path, data, highlightFilePerm); err != nil {
+		slog.Error("Ошибка сохранения яркого момента", "path", path, "err", err)
+		return
+	}
+	slog.Info("Яркий момент сохранён", "path", path, "frames", len(h.Frames))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolid(t *testing.T) {
+	tests := []struct {
+		name         string
+		player       Player
+		hitLingering bool
+		want         bool
+	}{
+		{"живой", Player{}, true, true},
+		{"подбитый", Player{Dead: true}, true, false},
+		{"отключившийся с -hit-lingering", Player{Disconnected: true, LingerHitUntil: time.Now().Add(time.Minute)}, true, true},
+		{"отключившийся без -hit-lingering", Player{Disconnected: true, LingerHitUntil: time.Now().Add(time.Minute)}, false, false},
+		{"ждущий переподключения после -disconnect-linger", Player{Disconnected: true, LingerHitUntil: time.Now().Add(-time.Second)}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.HitLingering = tt.hitLingering })
+			if got := solid(&tt.player); got != tt.want {
+				t.Fatalf("solid = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Снаряд пролетает сквозь обломки подбитого танка и летит дальше
+func TestProjectilePassesThroughDeadTank(t *testing.T) {
+	withConfig(t, nil)
+	game, shooter := firingTestGame(t)
+	wreck := addTarget(game, "plr-wreck", 500, 500)
+	wreck.Dead, wreck.Lives = true, 0
+	wreck.RespawnAt = game.gameNow().Add(time.Hour) // Обломки не исчезнут за этот тик
+	game.Projectiles["prj-1"] = &Projectile{ID: "prj-1", OwnerID: shooter.ID, X: 500, Y: 500, VX: 10,
+		Mask: DefaultProjectileMask, Damage: 1, CollisionRadius: ProjectileRadius, SpawnTime: game.gameNow()}
+
+	game.updateGameLogic(0.01, time.Now())
+
+	if !wreck.Dead || wreck.X != 500 {
+		t.Fatal("подбитый танк возродился или сдвинулся - проверка не имеет смысла")
+	}
+	if _, ok := game.Projectiles["prj-1"]; !ok {
+		t.Fatal("снаряд остановился о подбитый танк")
+	}
+	if wreck.Lives != 0 || shooter.Score != 0 {
+		t.Fatalf("попадание в подбитый танк засчитано: жизней %d, очков стрелка %d", wreck.Lives, shooter.Score)
+	}
+}
+
+func TestExplosionSparesDeadTank(t *testing.T) {
+	withConfig(t, nil)
+	game, shooter := firingTestGame(t)
+	wreck := addTarget(game, "plr-wreck", 510, 500)
+	wreck.Dead, wreck.Lives = true, 0
+	alive := addTarget(game, "plr-alive", 490, 500)
+
+	game.detonate(&Projectile{ID: "prj-1", OwnerID: shooter.ID, X: 500, Y: 500, Mask: DefaultProjectileMask, Damage: 1, ExplosionRadius: 60})
+
+	if wreck.Lives != 0 {
+		t.Fatalf("взрыв задел подбитый танк: жизней %d", wreck.Lives)
+	}
+	if alive.Lives != 2 {
+		t.Fatalf("взрыв не задел живой танк: жизней %d", alive.Lives)
+	}
+}
+
+// Танк, отключившийся дольше -disconnect-linger назад, но ещё ждущий переподключения, подбить нельзя
+func TestReconnectGraceTankCannotBeHit(t *testing.T) {
+	withConfig(t, func(c *Config) { c.DisconnectLinger, c.ReconnectGrace = 50*time.Millisecond, 30*time.Second })
+	url := startTestServer(t)
+	conn := dialTest(t, url)
+	id, _ := assignedSession(t, conn)
+	conn.Close()
+
+	game := rooms.defaultGame()
+	waitFor(t, game, "отключение игрока", func() bool { return game.Players[id].Disconnected })
+	shot := &Projectile{ID: "prj-1", OwnerID: "plr-enemy", Mask: DefaultProjectileMask, Damage: 1}
+
+	game.mutex.Lock()
+	tank := game.Players[id]
+	if !game.canHit(shot, tank) {
+		t.Error("в пределах -disconnect-linger отключившийся танк должен оставаться целью")
+	}
+	game.mutex.Unlock()
+
+	time.Sleep(100 * time.Millisecond) // -disconnect-linger прошёл, -reconnect-grace ещё нет
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	if game.Players[id] == nil {
+		t.Fatal("танк удалён до конца -reconnect-grace")
+	}
+	if game.canHit(shot, tank) {
+		t.Fatal("танк, ждущий переподключения, можно подбить")
+	}
+	lives := tank.Lives
+	game.detonate(&Projectile{ID: "prj-2", OwnerID: "plr-enemy", X: tank.X, Y: tank.Y, Mask: DefaultProjectileMask, Damage: 1, ExplosionRadius: 60})
+	if tank.Lives != lives {
+		t.Fatalf("взрыв задел танк, ждущий переподключения: жизней %d из %d", tank.Lives, lives)
+	}
+}
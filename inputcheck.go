@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// --- Проверка ввода клиентов ---
+
+// Сервер не доверяет числам из PlayerInput и ShootCommand. Сообщение с NaN/Inf отбрасывается
+// целиком: NaN в Atan2 даёт NaN-угол, который дальше попадает в скорость снарядов и позиции.
+// Конечные, но нелепые значения не отбрасываются, а зажимаются в разумные пределы.
+// Двоичный ввод (binary.go) проходит те же проверки после перевода в PlayerInput.
+
+// AimMargin - насколько точка прицела может выходить за арену, в долях её размера
+const AimMargin = 1.0
+
+var errNonFiniteInput = errors.New("нечисловое значение во вводе")
+
+// validateInput проверяет, что все числа ввода конечны
+func validateInput(in PlayerInput) error {
+	if !isFinite(in.AimX) || !isFinite(in.AimY) || !isFinite(in.StickX) || !isFinite(in.StickY) || !isFinite(in.AimAngle) {
+		return errNonFiniteInput
+	}
+	return nil
+}
+
+// validateShoot проверяет, что направление и заряд выстрела конечны
+func validateShoot(cmd ShootCommand) error {
+	if !isFinite(cmd.DirectionX) || !isFinite(cmd.DirectionY) || !isFinite(cmd.Charge) {
+		return errNonFiniteInput
+	}
+	return nil
+}
+
+// clampInput зажимает прицел в пределы арены с запасом AimMargin, а стик - в [-1, 1].
+// Вызывается под game.mutex после validateInput.
+func (game *GameState) clampInput(in *PlayerInput) {
+	w, h := float64(game.Bounds.Width), float64(game.Bounds.Height)
+	in.AimX = math.Max(-w*AimMargin, math.Min(in.AimX, w*(1+AimMargin)))
+	in.AimY = math.Max(-h*AimMargin, math.Min(in.AimY, h*(1+AimMargin)))
+	in.StickX = math.Max(-1, math.Min(in.StickX, 1))
+	in.StickY = math.Max(-1, math.Min(in.StickY, 1))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValidateInputRejectsNonFinite(t *testing.T) {
+	for _, in := range []PlayerInput{
+		{AimX: math.NaN()},
+		{AimY: math.Inf(1)},
+		{StickX: math.NaN()},
+		{StickY: math.Inf(-1)},
+	} {
+		if validateInput(in) == nil {
+			t.Errorf("ввод %+v принят", in)
+		}
+	}
+	if err := validateInput(PlayerInput{AimX: 1e12, StickY: -5}); err != nil {
+		t.Errorf("конечный ввод отброшен: %v", err)
+	}
+}
+
+func TestValidateShootRejectsNonFinite(t *testing.T) {
+	for _, cmd := range []ShootCommand{
+		{DirectionX: math.NaN(), DirectionY: 1},
+		{DirectionX: 1, DirectionY: math.Inf(1)},
+		{DirectionX: 1, Charge: math.NaN()},
+	} {
+		if validateShoot(cmd) == nil {
+			t.Errorf("выстрел %+v принят", cmd)
+		}
+	}
+}
+
+func TestClampInput(t *testing.T) {
+	game := newGameState(defaultMap())
+	w, h := float64(game.Bounds.Width), float64(game.Bounds.Height)
+	in := PlayerInput{AimX: 1e12, AimY: -1e12, StickX: 3, StickY: -3}
+	game.clampInput(&in)
+	if in.AimX != w*(1+AimMargin) || in.AimY != -h*AimMargin {
+		t.Errorf("прицел зажат в (%v, %v), ожидалось (%v, %v)", in.AimX, in.AimY, w*(1+AimMargin), -h*AimMargin)
+	}
+	if in.StickX != 1 || in.StickY != -1 {
+		t.Errorf("стик зажат в (%v, %v), ожидалось (1, -1)", in.StickX, in.StickY)
+	}
+}
+
+// Двоичный ввод проходит те же проверки: NaN-угол не должен попасть в прицел
+func TestBinaryInputRejectsNaN(t *testing.T) {
+	game := newGameState(defaultMap())
+	p := &Player{X: 100, Y: 100, Input: PlayerInput{AimX: 10, AimY: 20}}
+	err := game.applyBinaryInput(p, BinaryInput{Up: true, HasAim: true, AimAngle: math.NaN()})
+	if err == nil {
+		t.Fatal("ввод с NaN-прицелом принят")
+	}
+	if p.Input.Up || p.Input.AimByAngle || p.Input.AimX != 10 || p.Input.AimY != 20 {
+		t.Fatalf("отброшенный ввод изменил игрока: %+v", p.Input)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import "math"
+
+// --- Перехват снарядов ---
+
+// При -projectile-collisions снаряды противников, столкнувшиеся в полёте (расстояние меньше суммы
+// радиусов CollisionRadius), гасят друг друга: оба исчезают, а взрывоопасные взрываются. Так можно
+// сбить летящий в тебя снаряд. Снаряды одного владельца друг друга не трогают, снаряды союзников
+// в командном режиме - тоже, если не включён огонь по своим.
+//
+// Проверка идёт после движения всех снарядов в тике. Чтобы не сравнивать каждый снаряд с каждым,
+// снаряды раскладываются по ячейкам той же сетки, что и танки (см. spatialgrid.go).
+
+// opposing сообщает, могут ли снаряды a и b погасить друг друга. Вызывается под game.mutex.
+func (game *GameState) opposing(a, b *Projectile) bool {
+	if a.OwnerID == b.OwnerID {
+		return false
+	}
+	if config.FriendlyFire {
+		return true
+	}
+	ownerA, okA := game.Players[a.OwnerID]
+	ownerB, okB := game.Players[b.OwnerID]
+	return !okA || !okB || !teammates(ownerA, ownerB)
+}
+
+// interceptProjectiles гасит столкнувшиеся снаряды противников, кроме уже убранных в этом тике
+// (removed). Возвращает ID снарядов, которые должен удалить вызывающий. Вызывается под game.mutex.
+func (game *GameState) interceptProjectiles(removed []string) []string {
+	gone := make(map[string]bool, len(removed))
+	for _, id := range removed {
+		gone[id] = true
+	}
+	cells := make(map[gridCell][]*Projectile)
+	maxRadius := 0.0
+	for id, proj := range game.Projectiles {
+		if gone[id] || proj.Exploded {
+			continue
+		}
+		c := cellOf(proj.X, proj.Y)
+		cells[c] = append(cells[c], proj)
+		maxRadius = max(maxRadius, proj.CollisionRadius)
+	}
+
+	var intercepted []string
+	for _, bucket := range cells {
+		for _, a := range bucket {
+			if gone[a.ID] || a.Exploded {
+				continue
+			}
+			reach := a.CollisionRadius + maxRadius
+			from, to := cellOf(a.X-reach, a.Y-reach), cellOf(a.X+reach, a.Y+reach)
+		search:
+			for cx := from.x; cx <= to.x; cx++ {
+				for cy := from.y; cy <= to.y; cy++ {
+					for _, b := range cells[gridCell{cx, cy}] {
+						if b == a || gone[b.ID] || b.Exploded || !game.opposing(a, b) {
+							continue
+						}
+						if math.Hypot(a.X-b.X, a.Y-b.Y) >= a.CollisionRadius+b.CollisionRadius {
+							continue
+						}
+						gone[a.ID], gone[b.ID] = true, true
+						intercepted = append(intercepted, a.ID, b.ID)
+						for _, p := range []*Projectile{a, b} {
+							if p.explosive() && !p.Exploded {
+								for _, id := range game.detonate(p) {
+									gone[id] = true
+									intercepted = append(intercepted, id)
+								}
+							}
+						}
+						break search
+					}
+				}
+			}
+		}
+	}
+	return intercepted
+}
@@ -0,0 +1,30 @@
+package main
+
+import "time"
+
+// --- Метки времени для интерполяции ---
+
+// Состояние рассылается реже, чем считается (-broadcast-rate меньше -tick-rate), и клиент видит
+// не каждый тик. Чтобы клиент мог копить снимки в буфере и показывать их с правильными интервалами
+// независимо от дрожания сети, каждое состояние (gameState, gameStateDelta) несёт:
+//
+//	serverTime - момент тика, по которому собрано состояние: мс монотонных часов с запуска сервера
+//	tickMs     - интервал тика симуляции, мс
+//
+// serverTime - реальное время: на него не влияют ни перевод системных часов, ни масштаб времени
+// (-time-scale). В режиме -loop-mode fixed это момент, до которого досчитана симуляция: шаги,
+// догоняющие отставание за одну итерацию цикла, получают метки ровно через tickMs. Рассылка читает живое состояние под RLock, поэтому видит только завершённые тики,
+// и serverTime всегда соответствует последнему из них.
+
+// serverStart - начало отсчёта serverTime
+var serverStart = time.Now()
+
+// serverTimeMs переводит момент t в мс с запуска сервера (по монотонным часам, с долями мс)
+func serverTimeMs(t time.Time) float64 {
+	return float64(t.Sub(serverStart).Microseconds()) / 1000
+}
+
+// tickIntervalMs - интервал тика симуляции, мс
+func tickIntervalMs() float64 {
+	return 1000 / float64(config.TickRate)
+}
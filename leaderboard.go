@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --- Таблица рекордов ---
+
+// Когда игрок отключается, его итоговый счёт записывается в таблицу рекордов. Для каждого ника
+// хранится только лучший счёт. Таблица сохраняется в JSON-файл config.LeaderboardPath после каждого
+// изменения (через временный файл, чтобы оборванная запись не портила таблицу) и читается при запуске,
+// поэтому рекорды переживают перезапуск сервера. Без файла таблица живёт только в памяти.
+
+const (
+	LeaderboardSize     = 20 // Сколько лучших результатов отдаёт GET /leaderboard
+	leaderboardFilePerm = 0o644
+)
+
+// LeaderboardEntry - лучший результат одного ника
+type LeaderboardEntry struct {
+	Nickname   string `json:"nickname"`
+	Score      int    `json:"score"`
+	RecordedAt int64  `json:"recordedAt"` // Когда установлен рекорд, мс Unix
+}
+
+// Leaderboard - лучшие результаты по никам
+type Leaderboard struct {
+	mu      sync.Mutex
+	path    string // Файл таблицы ("" - только в памяти)
+	entries map[string]LeaderboardEntry
+}
+
+// leaderboard - таблица рекордов сервера (создаётся в main)
+var leaderboard *Leaderboard
+
+// loadLeaderboard читает таблицу из path. Отсутствующий файл - пустая таблица.
+func loadLeaderboard(path string) (*Leaderboard, error) {
+	lb := &Leaderboard{path: path, entries: make(map[string]LeaderboardEntry)}
+	if path == "" {
+		return lb, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lb, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблицы рекордов %s: %w", path, err)
+	}
+	var list []LeaderboardEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга таблицы рекордов %s: %w", path, err)
+	}
+	for _, e := range list {
+		if prev, ok := lb.entries[e.Nickname]; !ok || e.Score > prev.Score {
+			lb.entries[e.Nickname] = e
+		}
+	}
+	return lb, nil
+}
+
+// record запоминает результат, если он лучше прежнего для этого ника, и сохраняет таблицу.
+// Нулевые и отрицательные результаты не записываются.
+func (lb *Leaderboard) record(nickname string, score int, at time.Time) {
+	if score <= 0 {
+		return
+	}
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if prev, ok := lb.entries[nickname]; ok && prev.Score >= score {
+		return
+	}
+	lb.entries[nickname] = LeaderboardEntry{Nickname: nickname, Score: score, RecordedAt: at.UnixMilli()}
+	if err := lb.save(); err != nil {
+		slog.Error("Ошибка сохранения таблицы рекордов", "err", err)
+	}
+}
+
+// sorted возвращает результаты от лучшего к худшему (при равном счёте раньше - тот, кто раньше
+// установил рекорд). Вызывается под lb.mu.
+func (lb *Leaderboard) sorted() []LeaderboardEntry {
+	list := make([]LeaderboardEntry, 0, len(lb.entries))
+	for _, e := range lb.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].RecordedAt < list[j].RecordedAt
+	})
+	return list
+}
+
+// top возвращает n лучших результатов
+func (lb *Leaderboard) top(n int) []LeaderboardEntry {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	list := lb.sorted()
+	return list[:min(n, len(list))]
+}
+
+// save записывает таблицу в файл через временный файл в том же каталоге. Вызывается под lb.mu.
+func (lb *Leaderboard) save() error {
+	if lb.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(lb.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(lb.path), filepath.Base(lb.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // После успешного переименования файла уже нет
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), leaderboardFilePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), lb.path)
+}
+
+// handleLeaderboard - GET /leaderboard: LeaderboardSize лучших результатов
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(leaderboard.top(LeaderboardSize))
+}
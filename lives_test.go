@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGrantKillLives(t *testing.T) {
+	tests := []struct {
+		name              string
+		perKill, maxLives int
+		lives, want       int
+	}{
+		{"выключено", 0, 0, 1, 1},
+		{"жизнь за убийство", 1, 5, 2, 3},
+		{"не выше предела", 3, 5, 4, 5},
+		{"уже на пределе", 1, 5, 5, 5},
+		{"выше предела не отнимается", 1, 5, 7, 7},
+		{"предел по умолчанию - жизни класса", 1000, 0, 1, -1}, // -1 - стартовые жизни класса
+		{"погибший стрелок не получает", 1, 5, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, func(c *Config) { c.LivesPerKill, c.MaxLives = tt.perKill, tt.maxLives })
+			p := &Player{ID: "plr-1", Class: DefaultTankClass, Lives: tt.lives}
+			grantKillLives(p)
+			want := tt.want
+			if want < 0 {
+				want = tankClasses[DefaultTankClass].Lives // Зависит от -lives, поэтому после withConfig
+			}
+			if p.Lives != want {
+				t.Fatalf("жизней %d, ожидалось %d", p.Lives, want)
+			}
+		})
+	}
+}
+
+// Жизни даются только за убийство, а не за каждое попадание
+func TestKillGrantsLivesOnlyOnKill(t *testing.T) {
+	withConfig(t, func(c *Config) { c.LivesPerKill, c.MaxLives = 1, 10 })
+	game := newGameState(defaultMap())
+	shooter := &Player{ID: "plr-shooter", Class: DefaultTankClass, Lives: 2, Radius: PlayerRadius}
+	victim := &Player{ID: "plr-victim", Class: DefaultTankClass, Lives: 2, Radius: PlayerRadius}
+	game.Players[shooter.ID], game.Players[victim.ID] = shooter, victim
+
+	shot := &Projectile{ID: "prj-1", OwnerID: shooter.ID, Damage: 1}
+	game.applyProjectileHit(shot, victim)
+	if shooter.Lives != 2 {
+		t.Fatalf("за попадание без убийства выдано жизней: %d", shooter.Lives)
+	}
+	game.applyProjectileHit(shot, victim)
+	if shooter.Lives != 3 {
+		t.Fatalf("после убийства у стрелка %d жизней, ожидалось 3", shooter.Lives)
+	}
+}
+
+// Убийца на последней жизни получает жизнь и переживает следующее попадание,
+// а погибнув позже, возрождается как обычно, а не выбывает
+func TestEarnedLifeAtLastLife(t *testing.T) {
+	withConfig(t, func(c *Config) { c.LivesPerKill, c.MaxLives = 1, 5 })
+	game := newGameState(defaultMap())
+	game.Phase = PhasePlaying
+	killer := &Player{ID: "plr-killer", Class: DefaultTankClass, Lives: 1, Radius: PlayerRadius}
+	victim := &Player{ID: "plr-victim", Class: DefaultTankClass, Lives: 1, Radius: PlayerRadius}
+	enemy := &Player{ID: "plr-enemy", Class: DefaultTankClass, Lives: 3, Radius: PlayerRadius}
+	game.Players[killer.ID], game.Players[victim.ID], game.Players[enemy.ID] = killer, victim, enemy
+
+	game.applyProjectileHit(&Projectile{ID: "prj-1", OwnerID: killer.ID, Damage: 1}, victim)
+	if killer.Lives != 2 {
+		t.Fatalf("после убийства у убийцы %d жизней, ожидалось 2", killer.Lives)
+	}
+
+	shot := &Projectile{ID: "prj-2", OwnerID: enemy.ID, Damage: 1}
+	game.applyProjectileHit(shot, killer)
+	if killer.Dead || killer.Lives != 1 {
+		t.Fatalf("заработанная жизнь не спасла: dead=%v, жизней %d", killer.Dead, killer.Lives)
+	}
+	game.applyProjectileHit(shot, killer)
+	if !killer.Dead {
+		t.Fatal("убийца не погиб, потеряв последнюю жизнь")
+	}
+
+	game.Clock = killer.RespawnAt
+	game.updateDeadPlayer(killer)
+	if killer.Dead || killer.Eliminated || killer.Lives != tankClasses[DefaultTankClass].Lives {
+		t.Fatalf("после гибели: dead=%v, eliminated=%v, жизней %d; ожидалось возрождение с жизнями класса",
+			killer.Dead, killer.Eliminated, killer.Lives)
+	}
+}
+
+// Убийство, засчитанное уже погибшему стрелку (его снаряд долетел позже), жизней не даёт,
+// а неуязвимость после появления получению жизни не мешает
+func TestKillLivesWhileDeadOrProtected(t *testing.T) {
+	withConfig(t, func(c *Config) { c.LivesPerKill, c.MaxLives = 1, 5 })
+	game := newGameState(defaultMap())
+	game.Phase = PhasePlaying
+	shooter := &Player{ID: "plr-shooter", Class: DefaultTankClass, Lives: 1, Radius: PlayerRadius}
+	victim := &Player{ID: "plr-victim", Class: DefaultTankClass, Lives: 1, Radius: PlayerRadius}
+	game.Players[shooter.ID], game.Players[victim.ID] = shooter, victim
+
+	shooter.Lives = 0
+	game.killPlayer(shooter, "")
+	game.applyProjectileHit(&Projectile{ID: "prj-1", OwnerID: shooter.ID, Damage: 1}, victim)
+	if !victim.Dead || shooter.Kills != 1 {
+		t.Fatalf("убийство не засчитано: victim dead=%v, kills=%d", victim.Dead, shooter.Kills)
+	}
+	if shooter.Lives != 0 || !shooter.Dead {
+		t.Fatalf("погибший стрелок получил жизни: %d, dead=%v", shooter.Lives, shooter.Dead)
+	}
+
+	protected := &Player{ID: "plr-protected", Class: DefaultTankClass, Lives: 1, Radius: PlayerRadius,
+		InvulUntil: game.gameNow().Add(time.Hour)}
+	target := &Player{ID: "plr-target", Class: DefaultTankClass, Lives: 1, Radius: PlayerRadius}
+	game.Players[protected.ID], game.Players[target.ID] = protected, target
+	game.applyProjectileHit(&Projectile{ID: "prj-2", OwnerID: protected.ID, Damage: 1}, target)
+	if protected.Lives != 2 {
+		t.Fatalf("неуязвимый стрелок: жизней %d, ожидалось 2", protected.Lives)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// --- Журнал ---
+
+// Сервер пишет журнал через log/slog: сообщение на русском плюс поля ключ=значение
+// (player_id, projectile_id, event, room и т.д.), по которым журнал удобно фильтровать.
+// По умолчанию формат текстовый (LogFormatText) - его удобно читать глазами; в продакшене
+// -log-format json даёт по объекту JSON на строку для сборщиков журналов.
+//
+// Уровни: debug - частые боевые события (выстрелы, попадания) и разбор отдельных сообщений,
+// info - подключения, раунды, настройки, warn - ошибки клиентов и подозрительное состояние,
+// error - сбои сервера. -log-level задаёт нижний уровень, который попадает в журнал.
+
+// Форматы журнала
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// parseLogLevel разбирает уровень журнала: debug, info, warn или error
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("ожидалось debug, info, warn или error, получено %q", s)
+	}
+	return level, nil
+}
+
+// setupLogger настраивает журнал по config. Стандартный log тоже пишет через него (уровень info).
+func setupLogger() {
+	level, _ := parseLogLevel(config.LogLevel) // Уже проверено в validate
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if config.LogFormat == LogFormatJSON {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// fatal пишет ошибку в журнал и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
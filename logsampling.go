@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --- Выборочный журнал событий ---
+
+// В оживлённом бою строки о каждом выстреле и попадании забивают журнал. Для таких событий
+// можно писать только каждую N-ю строку: -log-sample shot=10,hit=5. Сами события и сообщения
+// клиентам не затрагиваются - прореживается только журнал.
+
+// logSampleRates - для каких событий журнал прореживается и каждая какая строка пишется
+var logSampleRates map[EventKind]int
+
+// parseLogSampling разбирает список "тип=N" через запятую
+func parseLogSampling(spec string) (map[EventKind]int, error) {
+	rates := make(map[EventKind]int)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("ожидалось тип=N, получено %q", item)
+		}
+		kind := EventKind(strings.TrimSpace(name))
+		if !loggedEvent(kind) {
+			return nil, fmt.Errorf("событие %q не пишется в журнал", kind)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("частота для %s должна быть целым числом не меньше 1, получено %q", kind, value)
+		}
+		rates[kind] = n
+	}
+	return rates, nil
+}
+
+// loggedEvent сообщает, пишет ли logEvent события этого типа
+func loggedEvent(kind EventKind) bool {
+	switch kind {
+	case EventShot, EventShotRejected, EventHit, EventKill, EventSpawn, EventExplosion, EventWallDestroyed:
+		return true
+	}
+	return false
+}
+
+// sampleLog сообщает, писать ли в журнал очередное событие типа kind
+func (game *GameState) sampleLog(kind EventKind) bool {
+	n := logSampleRates[kind]
+	if n <= 1 {
+		return true
+	}
+	seen := game.logSampleSeen[kind]
+	game.logSampleSeen[kind] = seen + 1
+	return seen%n == 0
+}
@@ -1,527 +1,1896 @@
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"math"
-	"math/rand"
-	"net/http"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-
-	"github.com/gorilla/websocket"
-)
-
-// --- Константы ---
-const (
-	TickRate         = 60 // Обновлений логики в секунду
-	BroadcastRate    = 30 // Отправок состояния клиентам в секунду
-	GameWidth        = 800
-	GameHeight       = 600
-	PlayerSpeed      = 150 // Пикселей в секунду
-	PlayerRadius     = 15
-	ProjectileSpeed  = 300 // Пикселей в секунду
-	ProjectileRadius = 3
-	ShootCooldown    = time.Millisecond * 500 // Задержка между выстрелами
-	InitialLives = 15 // изначальное колво жизней
-)
-
-// --- Структуры данных ---
-
-// PlayerInput хранит текущее состояние управляющих клавиш игрока
-type PlayerInput struct {
-	Up    bool    `json:"up"`
-	Down  bool    `json:"down"`
-	Left  bool    `json:"left"`
-	Right bool    `json:"right"`
-	AimX  float64 `json:"aimX"` // X координата прицела
-	AimY  float64 `json:"aimY"` // Y координата прицела
-}
-
-// Player представляет игрока
-type Player struct {
-	ID           string          `json:"id"`
-	X            float64         `json:"x"`
-	Y            float64         `json:"y"`
-	Color        string          `json:"color"`
-	Score        int             `json:"score"`
-	Lives        int             `json:"lives"` // добавлено после для жизни
-	Nickname     string          `json:"nickname"` // Добавлено поле для никнейма
-	BodyAngle    float64         `json:"bodyAngle"` // Угол корпуса танка
-	AimAngle     float64         `json:"aimAngle"` // Угол прицеливания игрока
-	Input        PlayerInput     `json:"-"`        // Текущий ввод игрока (обновляется клиентом)
-	LastShotTime time.Time       `json:"-"`        // Время последнего выстрела (серверная логика)
-	WantsToShoot bool            `json:"-"`        // Флаг, что игрок хочет выстрелить
-	Conn         *websocket.Conn `json:"-"`        // Ссылка на соединение
-	MessageChan  chan []byte     `json:"-"`        // Канал для отправки сообщений этому игроку
-}
-
-// ShootCommand передает направление выстрела
-type ShootCommand struct {
-    DirectionX float64 `json:"directionX"` // Нормализованный вектор X
-    DirectionY float64 `json:"directionY"` // Нормализованный вектор Y
-}
-
-// Projectile представляет снаряд
-type Projectile struct {
-	ID      string  `json:"id"`
-	OwnerID string  `json:"ownerId"`
-	X       float64 `json:"x"`
-	Y       float64 `json:"y"`
-	VX      float64 `json:"-"` // Скорость по X
-	VY      float64 `json:"-"` // Скорость по Y
-}
-
-// GameState хранит все состояние игры
-type GameState struct {
-	Players     map[string]*Player
-	Projectiles map[string]*Projectile
-	Bounds      struct{ Width, Height int }
-	mutex       sync.RWMutex // RWMutex для частых чтений (трансляция) и редких записей
-}
-
-// --- Сообщения WebSocket ---
-
-// ClientMessage - сообщение от клиента
-type ClientMessage struct {
-	Action  string          `json:"action"`  // "input", "shoot"
-	Payload json.RawMessage `json:"payload"` // PlayerInput для "input", ShootCommand для "shoot"
-}
-
-// ServerMessage - сообщение от сервера
-type ServerMessage struct {
-	Type    string      `json:"type"`    // "gameState", "assignId", "error"
-	Payload interface{} `json:"payload"` // Зависит от типа
-}
-
-// GameStatePayload - структура для отправки состояния клиентам
-type GameStatePayload struct {
-	Players     []*Player     `json:"players"`
-	Projectiles []*Projectile `json:"projectiles"`
-}
-
-// --- Глобальные переменные ---
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin:     func(r *http.Request) bool { return true }, // Разрешаем все источники
-}
-
-var game = &GameState{ // Единственный экземпляр игры
-	Players:     make(map[string]*Player),
-	Projectiles: make(map[string]*Projectile),
-	Bounds:      struct{ Width, Height int }{GameWidth, GameHeight},
-}
-
-var nextPlayerID = 1     // Простой счетчик ID игроков
-var nextProjectileID = 1 // Простой счетчик ID снарядов
-
-// --- Вспомогательные функции ---
-func generateID(prefix string, counter *int) string {
-	id := fmt.Sprintf("%s%d", prefix, *counter)
-	*counter++
-	return id
-}
-
-func randomColor() string {
-	return fmt.Sprintf("#%06x", rand.Intn(0xFFFFFF))
-}
-
-// calculateDirection вычисляет нормализованный направляющий вектор
-func calculateDirection(fromX, fromY, toX, toY float64) (float64, float64) {
-	dx := toX - fromX
-	dy := toY - fromY
-	length := math.Sqrt(dx*dx + dy*dy)
-
-	// Если длина слишком маленькая, стреляем вправо по умолчанию
-	if length < 0.001 {
-		return 1.0, 0.0
-	}
-
-	return dx / length, dy / length
-}
-
-// --- Логика Игры ---
-
-// gameLoop - основной цикл обновления логики игры
-func gameLoop() {
-	ticker := time.NewTicker(time.Second / TickRate)
-	defer ticker.Stop()
-
-	var lastTick time.Time = time.Now()
-
-	for range ticker.C {
-		now := time.Now()
-		deltaTime := now.Sub(lastTick).Seconds() // Время с прошлого тика
-		lastTick = now
-
-		updateGameLogic(deltaTime)
-	}
-}
-
-// updateGameLogic - обновляет состояние всех объектов игры
-func updateGameLogic(dt float64) {
-	game.mutex.Lock() // Полная блокировка на время обновления
-	defer game.mutex.Unlock()
-
-	projectilesToRemove := []string{}
-
-	// Обновляем игроков
-	for _, player := range game.Players {
-		// Движение
-		targetVX, targetVY := 0.0, 0.0
-		if player.Input.Up {
-			targetVY -= PlayerSpeed
-		}
-		if player.Input.Down {
-			targetVY += PlayerSpeed
-		}
-		if player.Input.Left {
-			targetVX -= PlayerSpeed
-		}
-		if player.Input.Right {
-			targetVX += PlayerSpeed
-		}
-
-		// Нормализация диагональной скорости (простая)
-		if targetVX != 0 && targetVY != 0 {
-			factor := 1.0 / math.Sqrt(2.0)
-			targetVX *= factor
-			targetVY *= factor
-		}
-
-		player.X += targetVX * dt
-		player.Y += targetVY * dt
-
-		// Ограничение по границам
-		player.X = math.Max(PlayerRadius, math.Min(float64(game.Bounds.Width-PlayerRadius), player.X))
-		player.Y = math.Max(PlayerRadius, math.Min(float64(game.Bounds.Height-PlayerRadius), player.Y))
-
-		// Обновление угла прицеливания на основе данных ввода
-		if player.Input.AimX != 0 || player.Input.AimY != 0 {
-			player.AimAngle = math.Atan2(player.Input.AimY-player.Y, player.Input.AimX-player.X)
-			
-			// Обновляем угол корпуса только при движении
-			if player.Input.Up || player.Input.Down || player.Input.Left || player.Input.Right {
-				player.BodyAngle = math.Atan2(targetVY, targetVX)
-			}
-		}
-
-		// Стрельба
-		if player.WantsToShoot && time.Since(player.LastShotTime) >= ShootCooldown {
-			player.LastShotTime = time.Now()
-			player.WantsToShoot = false // Сбрасываем флаг
-
-			// Определяем направление выстрела на основе угла прицеливания
-			dirX := math.Cos(player.AimAngle)
-			dirY := math.Sin(player.AimAngle)
-
-			projID := generateID("p", &nextProjectileID)
-			newProj := &Projectile{
-				ID:      projID,
-				OwnerID: player.ID,
-				X:       player.X, // Начальная позиция - центр игрока
-				Y:       player.Y,
-				VX:      dirX * ProjectileSpeed,
-				VY:      dirY * ProjectileSpeed,
-			}
-			game.Projectiles[projID] = newProj
-			log.Printf("Игрок %s выстрелил снаряд %s под углом %.2f", player.ID, projID, player.AimAngle)
-		}
-	}
-
-	// Обновляем снаряды и проверяем коллизии
-	for id, proj := range game.Projectiles {
-		proj.X += proj.VX * dt
-		proj.Y += proj.VY * dt
-
-		// Удаление за границами
-		if proj.X < 0 || proj.X > float64(game.Bounds.Width) || proj.Y < 0 || proj.Y > float64(game.Bounds.Height) {
-			projectilesToRemove = append(projectilesToRemove, id)
-			continue
-		}
-
-		// Проверка столкновения с игроками
-		for playerID, player := range game.Players {
-			if proj.OwnerID == playerID {
-				continue
-			} // Не сталкиваемся с собой
-
-			distSq := math.Pow(proj.X-player.X, 2) + math.Pow(proj.Y-player.Y, 2)
-			radiiSq := math.Pow(PlayerRadius+ProjectileRadius, 2)
-
-			if distSq < radiiSq {
-				log.Printf("Снаряд %s попал в игрока %s!", id, playerID)
-				projectilesToRemove = append(projectilesToRemove, id) // Удаляем снаряд
-
-				// Уменьшаем жизни игрока
-				player.Lives--
-				log.Printf("Игрок %s теряет жизнь. Осталось: %d", playerID, player.Lives)
-
-				// Начисляем очки стрелявшему
-				if shooter, ok := game.Players[proj.OwnerID]; ok {
-					shooter.Score++
-					log.Printf("Игрок %s получает очко! Счет: %d", shooter.ID, shooter.Score)
-				}
-				// TODO: Можно добавить эффект для игрока, в которого попали (например, респаун)
-				break // Снаряд может попасть только в одного игрока за тик
-			}
-		}
-	}
-
-	// Удаляем помеченные снаряды
-	for _, id := range projectilesToRemove {
-		delete(game.Projectiles, id)
-	}
-}
-
-// broadcastLoop - рассылает состояние игры клиентам
-func broadcastLoop() {
-	ticker := time.NewTicker(time.Second / BroadcastRate)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		sendGameStateToAll()
-	}
-}
-
-// sendGameStateToAll - готовит и отправляет состояние всем
-func sendGameStateToAll() {
-	game.mutex.RLock() // Блокировка чтения - другие читатели не блокируются
-	defer game.mutex.RUnlock()
-
-	// Создаем срезы для JSON (карты не гарантируют порядок в JSON)
-	playerList := make([]*Player, 0, len(game.Players))
-	for _, p := range game.Players {
-		playerList = append(playerList, p)
-	}
-	projectileList := make([]*Projectile, 0, len(game.Projectiles))
-	for _, p := range game.Projectiles {
-		projectileList = append(projectileList, p)
-	}
-
-	payload := GameStatePayload{
-		Players:     playerList,
-		Projectiles: projectileList,
-	}
-	msg := ServerMessage{Type: "gameState", Payload: payload}
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Ошибка маршалинга gameState: %v", err)
-		return
-	}
-
-	// Отправляем сообщение в канал каждого игрока
-	for _, player := range game.Players {
-		// Используем неблокирующую отправку, чтобы не зависнуть, если канал переполнен
-		select {
-		case player.MessageChan <- msgBytes:
-		default:
-			log.Printf("Предупреждение: Канал сообщений для игрока %s переполнен или закрыт.", player.ID)
-		}
-	}
-}
-
-// --- Обработка WebSocket ---
-
-// handleConnections - обрабатывает новые подключения
-func handleConnections(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("Ошибка обновления до WebSocket: %v", err)
-		return
-	}
-
-	log.Printf("Новое WebSocket соединение: %s", conn.RemoteAddr())
-
-	// Создаем нового игрока
-	game.mutex.Lock() // Блокируем для записи
-	playerID := generateID("plr", &nextPlayerID)
-	player := &Player{
-		ID:           playerID,
-		X:            float64(rand.Intn(GameWidth-PlayerRadius*2) + PlayerRadius), // Случайная позиция
-		Y:            float64(rand.Intn(GameHeight-PlayerRadius*2) + PlayerRadius),
-		Color:        randomColor(),
-		Score:        0,
-		Lives:        InitialLives, // устанавливаем начальное колво жизней
-		AimAngle:     0, // По умолчанию смотрим вправо
-		Conn:         conn,
-		MessageChan:  make(chan []byte, 32),          // Буферизованный канал
-		LastShotTime: time.Now().Add(-ShootCooldown), // Чтобы можно было стрелять сразу
-		Nickname:     "Player " + playerID, // Дефолтное имя
-	}
-	game.Players[playerID] = player
-	log.Printf("Создан игрок %s для %s", playerID, conn.RemoteAddr())
-	game.mutex.Unlock()
-
-	// Отправляем ID новому клиенту
-	assignMsg := ServerMessage{Type: "assignId", Payload: map[string]string{"id": playerID}}
-	assignBytes, _ := json.Marshal(assignMsg)
-	select {
-	case player.MessageChan <- assignBytes:
-	default: // Если не удалось отправить сразу - вероятно, канал уже закрыт
-	}
-
-	// Запускаем горутины для чтения и записи для этого клиента
-	go writer(player)
-	go reader(player)
-}
-
-// reader - читает сообщения от клиента
-func reader(player *Player) {
-	conn := player.Conn
-	playerID := player.ID
-
-	defer func() {
-		log.Printf("Reader завершается для игрока %s (%s)", playerID, conn.RemoteAddr())
-		game.mutex.Lock()
-		delete(game.Players, playerID) // Удаляем игрока из игры
-		close(player.MessageChan)      // Закрываем канал записи
-		conn.Close()                   // Закрываем соединение
-		log.Printf("Игрок %s удален.", playerID)
-		game.mutex.Unlock()
-	}()
-
-	conn.SetReadLimit(512)
-
-	for {
-		messageType, message, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("Неожиданная ошибка чтения для %s: %v", playerID, err)
-			} else {
-				log.Printf("Соединение %s закрыто: %v", playerID, err)
-			}
-			break
-		}
-
-		if messageType != websocket.TextMessage {
-			log.Printf("Получено не текстовое сообщение от %s", playerID)
-			continue
-		}
-
-		var msg ClientMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Ошибка парсинга JSON от %s: %v", playerID, err)
-			continue
-		}
-
-		// Обновляем состояние игрока (ввод/стрельба)
-		game.mutex.Lock()
-		if p, ok := game.Players[playerID]; ok {
-			switch msg.Action {
-			case "setNickname":
-				var nicknamePayload struct {
-					Nickname string `json:"nickname"`
-				}
-				if err := json.Unmarshal(msg.Payload, &nicknamePayload); err == nil {
-					p.Nickname = nicknamePayload.Nickname
-					log.Printf("Игрок %s установил никнейм: %s", playerID, p.Nickname)
-				}
-			case "input":
-				// Нужно аккуратно распаковать payload в PlayerInput
-				var inputPayload PlayerInput
-				if err := json.Unmarshal(msg.Payload, &inputPayload); err == nil {
-					p.Input = inputPayload
-					// Обновляем угол прицеливания
-					if inputPayload.AimX != 0 || inputPayload.AimY != 0 {
-						p.AimAngle = math.Atan2(inputPayload.AimY-p.Y, inputPayload.AimX-p.X)
-					}
-				} else {
-					log.Printf("Ошибка парсинга input payload от %s: %v", playerID, err)
-				}
-			case "shoot":
-				// Парсим команду выстрела с координатами прицела
-				var shootCmd ShootCommand
-				if err := json.Unmarshal(msg.Payload, &shootCmd); err == nil {
-					// Обновляем только угол пушки (aimAngle)
-					p.AimAngle = math.Atan2(shootCmd.DirectionY, shootCmd.DirectionX)
-					p.WantsToShoot = true
-				} else {
-					log.Printf("Ошибка парсинга shoot payload от %s: %v", playerID, err)
-					p.WantsToShoot = true // Стреляем в текущем направлении, если парсинг не удался
-				}
-			default:
-				log.Printf("Неизвестное действие '%s' от %s", msg.Action, playerID)
-			}
-		}
-		game.mutex.Unlock()
-	}
-}
-
-// writer - пишет сообщения из канала игрока в WebSocket соединение
-func writer(player *Player) {
-	conn := player.Conn
-	playerID := player.ID
-	messageChan := player.MessageChan
-
-	defer func() {
-		log.Printf("Writer завершается для игрока %s (%s)", playerID, conn.RemoteAddr())
-	}()
-
-	for message := range messageChan { // Цикл работает, пока канал не будет закрыт (в reader)
-		err := conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			log.Printf("Ошибка записи сообщения игроку %s: %v", playerID, err)
-			return
-		}
-	}
-}
-
-// --- Точка входа ---
-func main() {
-	rand.Seed(time.Now().UnixNano())
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-
-	log.Println("======================================")
-	log.Println(" Запуск сервера Динамической Игры ")
-	log.Println("======================================")
-
-	// Запускаем игровые циклы
-	go gameLoop()
-	go broadcastLoop()
-
-	// Настройка HTTP сервера с обработкой статических файлов
-	fs := http.FileServer(http.Dir("./static"))               // Обслуживаем файлы из текущей директории
-	http.Handle("/static/", http.StripPrefix("/static/", fs)) // Префикс для статических файлов
-
-	http.HandleFunc("/ws", handleConnections)
-	// новую ручку ктр будет выводить логин пользователя
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Проверяем существование файла
-		if r.URL.Path == "/" {
-			
-			http.ServeFile(w, r, "index.html")
-			return
-		}
-
-		// Для всех остальных запросов пробуем найти файл
-		path := filepath.Join(".", r.URL.Path)
-		fmt.Println(path)
-		_, err := os.Stat(path)
-		if os.IsNotExist(err) {
-			http.NotFound(w, r)
-			return
-		} else if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-
-		http.ServeFile(w, r, path)
-	})
-
-	log.Println("Сервер слушает на http://localhost:8080")
-	log.Println("Доступные файлы:")
-	files, _ := filepath.Glob("*")
-	for _, file := range files {
-		log.Printf(" - %s", file)
-	}
-
-	err := http.ListenAndServe(":8080", nil)
-	if err != nil {
-		log.Fatal("Критическая ошибка ListenAndServe: ", err)
-	}
-}
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// --- Константы ---
+const (
+	MinUpdateRate    = 1 // Минимальная частота обновлений, которую может запросить клиент
+	PlayerRadius     = 15
+	ProjectileSpeed  = 300 // Пикселей в секунду
+	ProjectileRadius = 3
+	BarrelLength     = 25 // Расстояние от центра танка до дула, откуда вылетает снаряд
+
+	// Частоты циклов, размер арены по умолчанию, скорость и жизни стандартного танка и скорострельность
+	// стандартной пушки задаются в Config (см. config.go)
+
+	ScoreboardKeyframeInterval = time.Second * 5 // Как часто таблица очков рассылается целиком, даже без изменений
+
+	PingWriteTimeout = time.Second * 5 // Сколько ждать отправки ping
+)
+
+// Режимы шага симуляции (config.LoopMode)
+const (
+	LoopVariable = "variable" // Один шаг на тик с dt по настенным часам
+	LoopFixed    = "fixed"    // Накопитель времени и шаги фиксированной длины 1/config.TickRate
+)
+
+// Причины отказа в выстреле
+const (
+	ShotRejectPlayerLimit = "playerProjectileLimit"
+	ShotRejectGlobalLimit = "globalProjectileLimit"
+	ShotRejectWeaponLimit = "weaponProjectileLimit" // Лимит снарядов текущего оружия (WeaponDef.MaxActive)
+	ShotRejectNoFire      = "noFire"                // Идёт разминка без стрельбы
+)
+
+const ProjectileDamage = 1 // Урон снаряда без заряда (жизни игрока или здоровье стены), см. charge.go
+
+// Слои столкновений. У каждого объекта есть слой, а у снаряда - маска слоёв, в которые он попадает.
+const (
+	LayerPlayer     uint32 = 1 << iota // Танки
+	LayerWall                          // Стены
+	LayerProjectile                    // Снаряды
+)
+
+// DefaultProjectileMask - обычный снаряд попадает в танки и стены
+const DefaultProjectileMask = LayerPlayer | LayerWall
+
+// collides сообщает, задевает ли маска mask объект слоя layer
+func collides(mask, layer uint32) bool {
+	return mask&layer != 0
+}
+
+// --- Структуры данных ---
+
+// TankClass описывает класс танка: размер корпуса, скорость и запас жизней
+type TankClass struct {
+	Name   string  `json:"name"`
+	Radius float64 `json:"radius"` // Радиус корпуса для столкновений
+	Speed  float64 `json:"speed"`  // Пикселей в секунду
+	Lives  int     `json:"lives"`  // Жизней при появлении
+}
+
+const DefaultTankClass = "medium" // Класс, который получает новый игрок
+
+// tankClasses - доступные классы танков
+var tankClasses = map[string]TankClass{
+	"scout":  {Name: "scout", Radius: 11, Speed: 200, Lives: 10}, // Маленький и быстрый
+	"medium": {Name: "medium", Radius: PlayerRadius},             // Стандартный танк (скорость и жизни - из config, см. applyConfig)
+	"heavy":  {Name: "heavy", Radius: 20, Speed: 110, Lives: 22}, // Большой и медленный
+}
+
+// PlayerInput хранит текущее состояние управляющих клавиш игрока
+type PlayerInput struct {
+	Up    bool    `json:"up"`
+	Down  bool    `json:"down"`
+	Left  bool    `json:"left"`
+	Right bool    `json:"right"`
+	AimX  float64 `json:"aimX"` // X координата прицела
+	AimY  float64 `json:"aimY"` // Y координата прицела
+
+	StickX float64 `json:"stickX"` // Вектор стика геймпада (-1..1), поворачивает башню с ограниченной скоростью
+	StickY float64 `json:"stickY"`
+
+	AimAngle   float64 `json:"-"` // Угол прицела из двоичного ввода, рад (см. binary.go)
+	AimByAngle bool    `json:"-"` // Прицел задан углом AimAngle, а не точкой AimX/AimY
+
+	Seq uint32 `json:"seq"` // Номер ввода на клиенте (0 - без номера), см. prediction.go
+}
+
+// Player представляет игрока
+type Player struct {
+	ID               string           `json:"id"`
+	X                float64          `json:"x"`
+	Y                float64          `json:"y"`
+	Color            string           `json:"color"`
+	Score            int              `json:"score"`
+	Lives            int              `json:"lives"`        // добавлено после для жизни
+	Nickname         string           `json:"nickname"`     // Добавлено поле для никнейма
+	BodyAngle        float64          `json:"bodyAngle"`    // Угол корпуса танка
+	AimAngle         float64          `json:"aimAngle"`     // Текущий угол башни
+	DesiredAimAngle  float64          `json:"-"`            // Угол, к которому поворачивается башня (см. aim.go)
+	Class            string           `json:"class"`        // Класс танка
+	Team             int              `json:"team"`         // Команда (0 - вне командного режима)
+	Bot              bool             `json:"bot"`          // Танком управляет сервер
+	Radius           float64          `json:"radius"`       // Радиус корпуса (зависит от класса)
+	Layer            uint32           `json:"-"`            // Слой столкновений танка
+	Weapon           string           `json:"weapon"`       // Текущее оружие
+	CooldownMs       int64            `json:"cooldownMs"`   // Перезарядка текущего оружия, мс
+	Kills            int              `json:"-"`            // Сколько раз добил противника
+	Deaths           int              `json:"-"`            // Сколько раз погиб
+	ShotsFired       int              `json:"-"`            // Выпущено снарядов
+	ShotsHit         int              `json:"-"`            // Снарядов попало в противника
+	BorderMisses     int              `json:"-"`            // Снарядов улетело за край арены (при config.BorderMisses)
+	Streak           int              `json:"-"`            // Убийств подряд без смерти
+	NoFireMs         int64            `json:"noFireMs"`     // Сколько ещё нельзя стрелять, мс (обновляется каждый тик)
+	CanFireAt        int64            `json:"canFireAt"`    // Когда танк сможет выстрелить, мс Unix (0 - сейчас, CanFireUnknown - неизвестно)
+	NoFireUntil      time.Time        `json:"-"`            // Личный запрет стрельбы после появления
+	InvulUntil       time.Time        `json:"-"`            // До какого момента танк неуязвим после появления
+	Invulnerable     bool             `json:"invulnerable"` // Танк сейчас неуязвим (обновляется каждый тик)
+	Dead             bool             `json:"dead"`         // Танк уничтожен и ждёт возрождения
+	Eliminated       bool             `json:"eliminated"`   // Погиб и выбыл до конца раунда (-last-standing)
+	RespawnMs        int64            `json:"respawnMs"`    // Сколько ждать до возрождения, мс (обновляется каждый тик)
+	SpectatingID     string           `json:"spectating"`   // За кем наблюдает погибший игрок (обычно убийца)
+	DiedAt           time.Time        `json:"-"`            // Когда танк был уничтожен
+	RespawnAt        time.Time        `json:"-"`            // Раньше этого момента возродиться нельзя
+	ManualRespawn    bool             `json:"-"`            // Возрождаться только по действию "respawn" (из настроек клиента)
+	RespawnRequested bool             `json:"-"`            // Игрок прислал "respawn" и возродится, как только позволит задержка
+	MaxProjectiles   int              `json:"-"`            // Сколько ближайших снарядов присылать клиенту (0 - все), из настроек клиента
+	ScoreboardColor  string           `json:"-"`            // Цвет в таблице очков, из настроек клиента (пусто - цвет танка)
+	UpdateInterval   time.Duration    `json:"-"`            // Как часто присылать клиенту состояние (0 - на каждой рассылке)
+	BinaryState      bool             `json:"-"`            // Присылать состояние в двоичном формате (см. binarystate.go)
+	NextStateSend    time.Time        `json:"-"`            // Когда клиенту пора прислать следующее состояние
+	delta            *deltaTracker    // Что последним отправлено клиенту при рассылке изменений (nil - ещё ничего)
+	brain            *botBrain        // Состояние управления ботом (nil у людей), см. bots.go
+	Speed            float64          `json:"-"`                // Скорость движения (зависит от класса)
+	VX, VY           float64          `json:"-"`                // Текущая скорость танка (см. movement.go)
+	PendingClass     string           `json:"-"`                // Класс, который будет применён при следующем появлении
+	Engaged          bool             `json:"-"`                // Игрок уже стрелял или получал урон с момента появления
+	Disconnected     bool             `json:"disconnected"`     // Соединение закрыто, танк доживает последние секунды на арене
+	LingerUntil      time.Time        `json:"-"`                // Когда отключившийся танк будет окончательно удалён
+	LingerHitUntil   time.Time        `json:"-"`                // До какого момента отключившийся танк ещё можно подбить (config.DisconnectLinger)
+	DisconnectReason DisconnectReason `json:"-"`                // Почему игрок отключился (заполняется при разрыве)
+	SessionToken     string           `json:"-"`                // Токен для переподключения (см. session.go)
+	IP               string           `json:"-"`                // Адрес клиента (для админки)
+	ConnectedAt      time.Time        `json:"-"`                // Когда открыто текущее соединение
+	Input            PlayerInput      `json:"-"`                // Текущий ввод игрока (обновляется клиентом)
+	InputSeq         uint32           `json:"-"`                // Номер последнего принятого ввода
+	LastProcessedSeq uint32           `json:"lastProcessedSeq"` // Номер ввода, учтённого в этом состоянии
+	LastShotTime     time.Time        `json:"-"`                // Время последнего выстрела (серверная логика)
+	WantsToShoot     bool             `json:"-"`                // Флаг, что игрок хочет выстрелить
+	ShotSeq          uint32           `json:"-"`                // Номер ожидающего выстрела из ShootCommand (новая команда заменяет старую)
+	ShotCharge       float64          `json:"-"`                // Заряд ожидающего выстрела (0..1)
+	ShotQueuedAt     time.Time        `json:"-"`                // Когда выстрел встал в очередь из-за лимита снарядов
+	Conn             *websocket.Conn  `json:"-"`                // Ссылка на соединение
+	MessageChan      chan []byte      `json:"-"`                // Канал для отправки сообщений этому игроку (nil после отключения)
+}
+
+// ShootCommand передает направление выстрела
+type ShootCommand struct {
+	DirectionX float64 `json:"directionX"` // Нормализованный вектор X
+	DirectionY float64 `json:"directionY"` // Нормализованный вектор Y
+	Seq        uint32  `json:"seq"`        // Порядковый номер выстрела на клиенте (0 - клиент не предсказывает выстрелы)
+	Charge     float64 `json:"charge"`     // Заряд выстрела 0..1 (см. charge.go)
+}
+
+// Projectile представляет снаряд
+type Projectile struct {
+	ID      string  `json:"id"`
+	OwnerID string  `json:"ownerId"`
+	Weapon  string  `json:"weapon"` // Оружие, из которого выпущен снаряд
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	VX      float64 `json:"-"`      // Скорость по X
+	VY      float64 `json:"-"`      // Скорость по Y
+	Wraps   int     `json:"-"`      // Сколько раз снаряд пересёк край арены в режиме "wrap"
+	Layer   uint32  `json:"-"`      // Слой самого снаряда
+	Mask    uint32  `json:"-"`      // Слои, в которые попадает снаряд
+	Damage  int     `json:"damage"` // Сколько жизней отнимает попадание (зависит от заряда)
+
+	OwnerChain []string `json:"-"` // Прежние владельцы снаряда по порядку (OwnerChain[0] - стрелок), см. credit.go
+	OriginX    float64  `json:"-"` // Откуда выпущен снаряд (для индикатора направления урона)
+	OriginY    float64  `json:"-"`
+
+	SpawnTime time.Time `json:"-"` // Когда выпущен (по игровым часам), для config.ProjectileLifetime
+	Traveled  float64   `json:"-"` // Пройденный путь, для config.ProjectileRange
+
+	ExplosionRadius float64 `json:"-"` // Радиус взрыва (0 - снаряд не взрывается), см. explosions.go
+	Exploded        bool    `json:"-"` // Снаряд уже взорвался (или ждёт взрыва в цепочке) в этом тике
+
+	CollisionRadius float64 `json:"-"`      // Радиус для расчёта попаданий
+	VisualRadius    float64 `json:"radius"` // Радиус отрисовки на клиенте (на попадания не влияет)
+}
+
+// GameState хранит все состояние игры
+type GameState struct {
+	Players     map[string]*Player
+	Spectators  map[string]*Spectator // Зрители без танков (см. spectators.go)
+	Projectiles map[string]*Projectile
+	Bounds      struct{ Width, Height int }
+	Arena       Arena        // Граница арены (прямоугольник или круг, см. arena.go)
+	Map         *MapDef      // Активная карта: размеры и физика арены
+	Walls       []*Wall      // Текущие стены (разрушаемые могут исчезать)
+	mutex       sync.RWMutex // RWMutex для частых чтений (трансляция) и редких записей
+
+	Phase       GamePhase // Текущая фаза игры
+	PhaseEndsAt time.Time // Когда закончится текущая фаза (нулевое время - бессрочно)
+
+	Clock     time.Time // Игровые часы: идут быстрее или медленнее настоящих при TimeScale != 1 (см. timescale.go)
+	tickTime  time.Time // Когда начался последний тик (настоящее время, см. interpolation.go)
+	TimeScale float64   // Множитель шага симуляции (1 - обычная скорость)
+
+	scoreboardDirty    bool      // Таблица очков изменилась с последней рассылки
+	scoreboardVersion  int       // Версия таблицы очков, растёт при каждом изменении
+	scoreboardSentTime time.Time // Время последней рассылки таблицы очков
+	lastRebalance      time.Time // Время последней проверки баланса команд
+	NoFireUntil        time.Time // До какого момента стрельба запрещена всем (разминка в начале раунда)
+
+	events  []GameEvent // События, накопленные с прошлого тика (см. events.go)
+	feed    []FeedEvent // Лента событий для ближайшей рассылки (см. feed.go)
+	grid    playerGrid  // Игроки по ячейкам для поиска столкновений со снарядами
+	nextMap *MapDef     // Карта следующего раунда, выбранная при переходе в перерыв (nil - та же)
+
+	botSkill      float64            // Текущий уровень ботов (0..1), см. difficulty.go
+	rotationIndex int                // Индекс активной карты в mapRotation
+	logSampleSeen map[EventKind]int  // Сколько событий каждого типа уже прошло через журнал (только игровой цикл)
+	highlights    *HighlightRecorder // Рекордер ярких моментов (nil, если запись выключена)
+	stateSeq      uint64             // Номер текущей рассылки состояния (меняется только рассылкой)
+	stateSnapshot uint64             // Номер последнего разбитого снимка (меняется только рассылкой)
+	pendingJoins  int                // Зрители, вступающие в игру: их места уже заняты (см. joinSpectator)
+}
+
+// --- Сообщения WebSocket ---
+
+// DisconnectReason - причина отключения игрока
+type DisconnectReason string
+
+const (
+	DisconnectClientClosed DisconnectReason = "clientClosed" // Клиент корректно закрыл соединение
+	DisconnectAbnormal     DisconnectReason = "abnormal"     // Соединение оборвалось без закрывающего кадра
+	DisconnectReadError    DisconnectReason = "readError"    // Ошибка чтения (протокол, превышен лимит размера и т.п.)
+	DisconnectWriteError   DisconnectReason = "writeError"   // Не удалось отправить сообщение клиенту
+	DisconnectFlood        DisconnectReason = "flood"        // Клиент слишком долго превышал лимит частоты сообщений
+	DisconnectTimeout      DisconnectReason = "timeout"      // Клиент не ответил на ping за config.PongTimeout
+	DisconnectKicked       DisconnectReason = "kicked"       // Отключён админом (см. admin.go)
+)
+
+// ClientMessage - сообщение от клиента
+type ClientMessage struct {
+	Action  string          `json:"action"`  // "input", "shoot"
+	Payload json.RawMessage `json:"payload"` // PlayerInput для "input", ShootCommand для "shoot"
+}
+
+// ServerMessage - сообщение от сервера
+type ServerMessage struct {
+	Type    string      `json:"type"`    // "gameState", "assignId", "error"
+	Payload interface{} `json:"payload"` // Зависит от типа
+}
+
+// ErrorPayload - описание ошибки для клиента
+type ErrorPayload struct {
+	Code    string `json:"code"`             // Машиночитаемый код ошибки
+	Message string `json:"message"`          // Описание для человека
+	Action  string `json:"action,omitempty"` // Действие клиента, вызвавшее ошибку
+}
+
+// ShotRejectedPayload - причина, по которой выстрел не состоялся
+type ShotRejectedPayload struct {
+	Reason string `json:"reason"`
+	Seq    uint32 `json:"seq,omitempty"` // Номер отклонённого выстрела из ShootCommand
+}
+
+// DamageDirectionPayload - откуда пришёл урон (отправляется только пострадавшему)
+type DamageDirectionPayload struct {
+	Angle      float64 `json:"angle"`      // Угол от пострадавшего к точке выстрела, радианы
+	Damage     int     `json:"damage"`     // Сколько жизней отнято
+	AttackerID string  `json:"attackerId"` // Владелец снаряда
+}
+
+// ShotConfirmedPayload - подтверждение выстрела стрелявшему: какой снаряд сервера соответствует
+// предсказанному клиентом выстрелу Seq. Если снаряд попал в упор, в состоянии игры он не появится.
+type ShotConfirmedPayload struct {
+	Seq          uint32 `json:"seq"`
+	ProjectileID string `json:"projectileId"`
+}
+
+// ScoreboardEntry - строка таблицы очков
+type ScoreboardEntry struct {
+	ID       string `json:"id"`
+	Nickname string `json:"nickname"`
+	Score    int    `json:"score"`
+	Lives    int    `json:"lives"`
+	Kills    int    `json:"kills"`
+	Deaths   int    `json:"deaths"`
+	Team     int    `json:"team"`
+	Color    string `json:"color"` // Цвет для таблицы очков (ScoreboardColor или цвет танка)
+}
+
+// ScoreboardPayload - таблица очков с версией, чтобы клиент понимал, актуальна ли его копия
+type ScoreboardPayload struct {
+	Version int               `json:"version"`
+	Entries []ScoreboardEntry `json:"entries"`
+}
+
+// ClientSettings - настройки клиента, присылаемые действием "settings". Пропущенные поля не меняются.
+type ClientSettings struct {
+	MaxProjectiles *int `json:"maxProjectiles"` // Сколько ближайших снарядов присылать (0 - все)
+	UpdateRate     *int `json:"updateRate"`     // Сколько раз в секунду присылать состояние (MinUpdateRate..config.BroadcastRate)
+
+	ScoreboardColor *string `json:"scoreboardColor"` // Цвет в таблице очков в формате #rrggbb (пустая строка - цвет танка)
+	ManualRespawn   *bool   `json:"manualRespawn"`   // Возрождаться только по действию "respawn"
+}
+
+// TimeSyncPayload - ответ на запрос синхронизации часов (в стиле NTP).
+// Клиент вычисляет RTT = (t3 - t0) - (sendTime - receiveTime) и смещение часов
+// offset = ((receiveTime - t0) + (sendTime - t3)) / 2, где t3 - время получения ответа.
+type TimeSyncPayload struct {
+	ClientTime        float64 `json:"clientTime"`        // Время клиента из запроса (t0), возвращается как есть
+	ServerReceiveTime int64   `json:"serverReceiveTime"` // Время получения запроса сервером, мс Unix
+	ServerSendTime    int64   `json:"serverSendTime"`    // Время отправки ответа, мс Unix
+}
+
+// GameStatePayload - структура для отправки состояния клиентам
+type GameStatePayload struct {
+	Players       []*Player     `json:"players"`
+	Projectiles   []*Projectile `json:"projectiles"`
+	Phase         GamePhase     `json:"phase"`
+	PhaseEndsInMs int64         `json:"phaseEndsInMs"`        // Обратный отсчёт до конца фазы (0 - бессрочно)
+	NoFireMs      int64         `json:"noFireMs"`             // Обратный отсчёт разминки без стрельбы (0 - стрелять можно)
+	TeamScores    map[int]int   `json:"teamScores,omitempty"` // Суммарный счёт по номеру команды (в командном режиме)
+	Seq           uint64        `json:"seq,omitempty"`        // Номер рассылки (при рассылке изменений, см. delta.go)
+	Events        []FeedEvent   `json:"events,omitempty"`     // События с прошлой рассылки (см. feed.go)
+	RadarBlips    []RadarBlip   `json:"radarBlips,omitempty"` // Все живые танки для миникарты (при -radar, см. radar.go)
+	ServerTime    float64       `json:"serverTime"`           // Момент тика, по которому собрано состояние (см. interpolation.go)
+	TickMs        float64       `json:"tickMs"`               // Интервал тика симуляции, мс
+}
+
+// --- Глобальные переменные ---
+var upgrader = websocket.Upgrader{ // Размеры буферов берутся из config в main
+	CheckOrigin: func(r *http.Request) bool { return true }, // Разрешаем все источники
+}
+
+// Счётчики ID делят все комнаты, поэтому они атомарные и не зависят от блокировок вызывающего
+var nextPlayerID atomic.Int64     // Последний выданный номер игрока
+var nextProjectileID atomic.Int64 // Последний выданный номер снаряда
+var nextSpectatorID atomic.Int64  // Последний выданный номер зрителя
+
+// idEpoch - короткий случайный суффикс, уникальный для запуска сервера.
+// Благодаря ему ID не повторяются после перезапуска, когда счётчики начинаются с 1.
+var idEpoch = randomBase36(3)
+
+// --- Вспомогательные функции ---
+
+// generateID выдаёт ID вида "plr12-k3f": префикс, номер и суффикс запуска
+func generateID(prefix string, counter *atomic.Int64) string {
+	return fmt.Sprintf("%s%d-%s", prefix, counter.Add(1), idEpoch)
+}
+
+// randomBase36 возвращает случайную строку из n символов [0-9a-z]
+func randomBase36(n int) string {
+	const alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func randomColor() string {
+	return fmt.Sprintf("#%06x", rand.Intn(0xFFFFFF))
+}
+
+// validColor проверяет, что цвет задан в том же формате, что и цвет танка: #rrggbb
+func validColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(s[1:], 16, 32)
+	return err == nil
+}
+
+// scoreboardColor - цвет игрока в таблице очков
+func scoreboardColor(p *Player) string {
+	if p.ScoreboardColor != "" {
+		return p.ScoreboardColor
+	}
+	return p.Color
+}
+
+// randomPosition возвращает случайную точку, в которой танк радиуса radius целиком помещается на арене
+func (game *GameState) randomPosition(radius float64) (float64, float64) {
+	const attempts = 10 // На круглой арене часть точек описанного прямоугольника лежит снаружи
+	var x, y float64
+	for range attempts {
+		x = radius + rand.Float64()*(float64(game.Bounds.Width)-radius*2)
+		y = radius + rand.Float64()*(float64(game.Bounds.Height)-radius*2)
+		if cx, cy := game.Arena.Clamp(x, y, radius); cx == x && cy == y {
+			return x, y
+		}
+	}
+	return game.Arena.Clamp(x, y, radius)
+}
+
+// applyTankClass применяет параметры класса к игроку и восстанавливает ему жизни
+func applyTankClass(player *Player, class TankClass) {
+	player.Class = class.Name
+	player.Radius = class.Radius
+	player.Speed = class.Speed
+	player.Lives = class.Lives
+	player.PendingClass = ""
+}
+
+// calculateDirection вычисляет нормализованный направляющий вектор
+func calculateDirection(fromX, fromY, toX, toY float64) (float64, float64) {
+	dx := toX - fromX
+	dy := toY - fromY
+	length := math.Sqrt(dx*dx + dy*dy)
+
+	// Если длина слишком маленькая, стреляем вправо по умолчанию
+	if length < 0.001 {
+		return 1.0, 0.0
+	}
+
+	return dx / length, dy / length
+}
+
+// queueMessage - неблокирующая отправка готового сообщения в канал игрока.
+// Вызывается под game.mutex, чтобы канал не был закрыт во время отправки.
+func queueMessage(player *Player, msgBytes []byte) {
+	if player.MessageChan == nil {
+		return // Соединения уже нет (танк доживает после отключения)
+	}
+	// Используем неблокирующую отправку, чтобы не зависнуть, если канал переполнен
+	select {
+	case player.MessageChan <- msgBytes:
+	default:
+		slog.Warn("Канал сообщений игрока переполнен или закрыт", "player_id", player.ID)
+	}
+}
+
+// sendToPlayer - отправляет сообщение одному игроку
+func sendToPlayer(player *Player, msg ServerMessage) {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Ошибка маршалинга сообщения", "type", msg.Type, "err", err)
+		return
+	}
+	queueMessage(player, msgBytes)
+}
+
+// broadcastMessage - отправляет сообщение всем игрокам (сериализуя его один раз)
+func (game *GameState) broadcastMessage(msg ServerMessage) {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Ошибка маршалинга сообщения", "type", msg.Type, "err", err)
+		return
+	}
+	for _, player := range game.Players {
+		queueMessage(player, msgBytes)
+	}
+	game.queueSpectators(msgBytes)
+}
+
+// disconnectPlayer закрывает соединение игрока, запоминая причину. Reader получит ошибку чтения
+// и выполнит обычную очистку. Вызывается под game.mutex.
+func disconnectPlayer(player *Player, reason DisconnectReason) {
+	if player.DisconnectReason == "" {
+		player.DisconnectReason = reason
+	}
+	if player.Conn != nil {
+		player.Conn.Close()
+	}
+}
+
+// readDisconnectReason определяет причину отключения по ошибке чтения
+func readDisconnectReason(err error) DisconnectReason {
+	var closeErr *websocket.CloseError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return DisconnectTimeout // Истёк срок чтения: ни сообщений, ни pong
+	case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived):
+		return DisconnectClientClosed
+	case websocket.IsCloseError(err, websocket.CloseAbnormalClosure), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return DisconnectAbnormal
+	case errors.As(err, &closeErr):
+		return DisconnectAbnormal
+	default:
+		return DisconnectReadError
+	}
+}
+
+// removePlayer окончательно убирает игрока из игры. Вызывается под game.mutex.
+func (game *GameState) removePlayer(playerID string) {
+	delete(game.Players, playerID)
+	if config.DespawnOnDisconnect {
+		game.removeProjectilesOf(playerID)
+	}
+	game.scoreboardDirty = true
+	slog.Info("Игрок удалён", "player_id", playerID)
+}
+
+// removeProjectilesOf удаляет все снаряды, выпущенные игроком ownerID. Вызывается под game.mutex.
+func (game *GameState) removeProjectilesOf(ownerID string) int {
+	removed := 0
+	for id, proj := range game.Projectiles {
+		if proj.OwnerID == ownerID {
+			delete(game.Projectiles, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// projectileLimitReason возвращает причину, по которой игрок сейчас не может выпустить снаряд,
+// или пустую строку, если лимиты не достигнуты. Вызывается под game.mutex.
+func (game *GameState) projectileLimitReason(player *Player) string {
+	if config.MaxProjectilesTotal > 0 && len(game.Projectiles) >= config.MaxProjectilesTotal {
+		return ShotRejectGlobalLimit
+	}
+	weaponLimit := weapons[player.Weapon].MaxActive
+	if config.MaxProjectilesPerPlayer > 0 || weaponLimit > 0 {
+		owned, ownedOfWeapon := 0, 0
+		for _, proj := range game.Projectiles {
+			if proj.OwnerID != player.ID {
+				continue
+			}
+			owned++
+			if proj.Weapon == player.Weapon {
+				ownedOfWeapon++
+			}
+		}
+		if config.MaxProjectilesPerPlayer > 0 && owned >= config.MaxProjectilesPerPlayer {
+			return ShotRejectPlayerLimit
+		}
+		if weaponLimit > 0 && ownedOfWeapon >= weaponLimit {
+			return ShotRejectWeaponLimit
+		}
+	}
+	return ""
+}
+
+// --- Логика Игры ---
+
+// gameLoop - основной цикл обновления логики игры (до закрытия stop)
+func (game *GameState) gameLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second / time.Duration(config.TickRate))
+	defer ticker.Stop()
+
+	// Время тика меряется только как разница с моментом запуска цикла: time.Since использует
+	// монотонные часы, поэтому перевод системных часов не влияет на dt
+	start := time.Now()
+	var lastElapsed time.Duration
+	fixedDt := 1.0 / float64(config.TickRate)
+	accumulator := 0.0 // Накопленное, но ещё не просимулированное время (режим LoopFixed)
+	lastDifficultyCheck := start
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		gameLoopStarted.Store(true)
+		if config.AdaptiveBots && time.Since(lastDifficultyCheck) >= DifficultyInterval {
+			lastDifficultyCheck = time.Now()
+			game.adjustBotDifficulty()
+		}
+
+		elapsed := time.Since(start)
+		deltaTime := (elapsed - lastElapsed).Seconds() // Время с прошлого тика
+		lastElapsed = elapsed
+
+		if config.LoopMode != LoopFixed {
+			game.dispatchEvents(game.updateGameLogic(deltaTime, start.Add(elapsed)))
+			continue
+		}
+
+		// Фиксированный шаг: симуляция не зависит от неравномерности тикера
+		accumulator += deltaTime
+		steps := 0
+		for accumulator >= fixedDt && steps < config.MaxStepsPerLoop {
+			accumulator -= fixedDt
+			// Метка шага - момент, до которого досчитана симуляция: шаги догона идут ровно через fixedDt,
+			// а не получают одно и то же настоящее время
+			tickTime := start.Add(elapsed - time.Duration(accumulator*float64(time.Second)))
+			game.dispatchEvents(game.updateGameLogic(fixedDt, tickTime))
+			steps++
+		}
+		if accumulator >= fixedDt {
+			// Сервер не успевает - отбрасываем отставание, чтобы не копить его бесконечно
+			slog.Warn("Симуляция отстаёт", "skipped_steps", math.Floor(accumulator/fixedDt))
+			accumulator = math.Mod(accumulator, fixedDt)
+		}
+	}
+}
+
+// updateGameLogic - обновляет состояние всех объектов игры на шаг dt, заканчивающийся в момент
+// tickTime, и возвращает события этого тика. Сама функция ничего не рассылает: побочные эффекты
+// выполняет dispatchEvents.
+func (game *GameState) updateGameLogic(dt float64, tickTime time.Time) []GameEvent {
+	game.mutex.Lock() // Полная блокировка на время обновления
+	defer game.mutex.Unlock()
+	game.tickTime = tickTime // Рассылка увидит эту метку только вместе с результатом тика
+
+	timer := startPhaseTimer()
+	defer timer.observe()
+	defer observeTick(time.Now())
+
+	// Отрицательный (или некорректный) шаг двигал бы объекты назад - такой тик только обновляет состояние без движения
+	if !(dt > 0) {
+		dt = 0
+	}
+	dt = game.advanceClock(dt)
+
+	projectilesToRemove := []string{}
+	wallsChanged := false
+	game.ackInputs()
+
+	// Убираем отключившиеся танки, время показа которых истекло
+	for id, player := range game.Players {
+		if player.Disconnected && time.Now().After(player.LingerUntil) {
+			game.removePlayer(id)
+			game.emit(GameEvent{Kind: EventPlayerRemoved, PlayerID: id})
+		}
+	}
+
+	// Перерыв между раундами: никто не двигается и не стреляет
+	if game.Phase == PhaseIntermission {
+		for _, player := range game.Players {
+			player.CanFireAt = CanFireUnknown
+		}
+		if game.gameNow().After(game.PhaseEndsAt) {
+			game.startRound()
+		}
+		return game.takeEvents()
+	}
+	game.checkRoundStart()
+
+	game.updateBots() // Боты "присылают" ввод до обработки игроков, как и клиенты
+
+	// Обновляем игроков
+	for _, player := range game.Players {
+		if player.Dead {
+			game.updateDeadPlayer(player)
+			continue
+		}
+
+		// Движение
+		targetVX, targetVY := 0.0, 0.0
+		if player.Input.Up {
+			targetVY -= player.Speed
+		}
+		if player.Input.Down {
+			targetVY += player.Speed
+		}
+		if player.Input.Left {
+			targetVX -= player.Speed
+		}
+		if player.Input.Right {
+			targetVX += player.Speed
+		}
+
+		// Нормализация диагональной скорости (простая)
+		if targetVX != 0 && targetVY != 0 {
+			factor := 1.0 / math.Sqrt(2.0)
+			targetVX *= factor
+			targetVY *= factor
+		}
+
+		// Скорость тянется к направлению ввода, а не меняется мгновенно (см. movement.go)
+		accelerate(player, targetVX, targetVY, dt)
+		game.moveTank(player, player.VX*dt, player.VY*dt)
+
+		// Ограничение по границам (или перенос на другую сторону на "закольцованных" картах)
+		game.keepInArena(player)
+
+		// Обновление угла прицеливания на основе данных ввода (точка прицела или стик)
+		updateAim(player, dt)
+
+		// Обновляем угол корпуса только при движении
+		if targetVX != 0 || targetVY != 0 {
+			player.BodyAngle = math.Atan2(targetVY, targetVX)
+		}
+		timer.lap(PhaseMovement)
+
+		// Разминка: оружие заблокировано, выстрел отклоняется
+		player.NoFireMs = game.noFireRemainingMs(player)
+		player.Invulnerable = game.gameNow().Before(player.InvulUntil)
+		if targetVX != 0 || targetVY != 0 {
+			breakSpawnProtection(player, ProtectionBreakMove)
+		}
+		block, blockedUntil := game.fireBlock(player)
+		player.CanFireAt = game.canFireAt(block, blockedUntil)
+		if player.WantsToShoot && block == ShotRejectNoFire {
+			player.WantsToShoot = false
+			game.emitMessage(GameEvent{Kind: EventShotRejected, PlayerID: player.ID, Detail: ShotRejectNoFire},
+				player.ID, ServerMessage{Type: "shotRejected", Payload: ShotRejectedPayload{Reason: ShotRejectNoFire, Seq: player.ShotSeq}})
+			player.ShotSeq = 0
+		}
+
+		// Стрельба. Пока идёт перезарядка, флаг остаётся взведённым и выстрел произойдёт по её окончании.
+		if player.WantsToShoot && block != FireCooldown {
+			if block != "" {
+				// Лимит снарядов: либо ждём освобождения слота, либо сразу сообщаем об отказе
+				if config.ShotQueue && player.ShotQueuedAt.IsZero() {
+					player.ShotQueuedAt = game.gameNow()
+				}
+				if !config.ShotQueue || game.gameNow().Sub(player.ShotQueuedAt) > config.ShotQueueWindow {
+					player.WantsToShoot = false
+					player.ShotQueuedAt = time.Time{}
+					game.emitMessage(GameEvent{Kind: EventShotRejected, PlayerID: player.ID, Detail: block},
+						player.ID, ServerMessage{Type: "shotRejected", Payload: ShotRejectedPayload{Reason: block, Seq: player.ShotSeq}})
+					player.ShotSeq = 0
+				}
+				continue
+			}
+
+			if !turretAligned(player) {
+				continue // Башня ещё поворачивается - выстрел подождёт
+			}
+
+			player.LastShotTime = game.gameNow()
+			player.WantsToShoot = false // Сбрасываем флаг
+			player.Engaged = true
+			breakSpawnProtection(player, ProtectionBreakFire)
+			player.ShotsFired++
+			player.ShotQueuedAt = time.Time{}
+			player.CanFireAt = game.canFireAt(game.fireBlock(player)) // Началась перезарядка
+
+			// Определяем направление выстрела на основе угла прицеливания
+			dirX := math.Cos(player.AimAngle)
+			dirY := math.Sin(player.AimAngle)
+			speed := ProjectileSpeed * game.Map.Physics.ProjectileSpeedMultiplier * chargedSpeed(player.ShotCharge)
+
+			projID := generateID("p", &nextProjectileID)
+			originX, originY := player.X+dirX*BarrelLength, player.Y+dirY*BarrelLength // Дуло пушки
+			collisionRadius, visualRadius := weapons[player.Weapon].projectileRadii()
+			newProj := &Projectile{
+				ID:      projID,
+				OwnerID: player.ID,
+				Weapon:  player.Weapon,
+				Layer:   LayerProjectile,
+
+				ExplosionRadius: weapons[player.Weapon].ExplosionRadius,
+				Mask:            DefaultProjectileMask,
+				Damage:          chargedDamage(player.ShotCharge),
+
+				CollisionRadius: collisionRadius,
+				VisualRadius:    visualRadius,
+				X:               originX, // Начальная позиция - дуло пушки
+				Y:               originY,
+				OriginX:         originX,
+				OriginY:         originY,
+				VX:              dirX * speed,
+				VY:              dirY * speed,
+				SpawnTime:       game.gameNow(),
+			}
+			player.ShotCharge = 0
+			shot := GameEvent{Kind: EventShot, PlayerID: player.ID, ProjectileID: projID, X: originX, Y: originY}
+			if player.ShotSeq != 0 {
+				// Клиент предсказал этот выстрел - сообщаем ему ID настоящего снаряда
+				game.emitMessage(shot, player.ID, ServerMessage{Type: "shotConfirmed", Payload: ShotConfirmedPayload{Seq: player.ShotSeq, ProjectileID: projID}})
+				player.ShotSeq = 0
+			} else {
+				game.emit(shot)
+			}
+			game.emitSound(shot)
+			shotsFiredTotal.Inc()
+
+			// Противник вплотную к стволу получает попадание сразу, иначе снаряд появился бы уже за ним
+			if victim := game.barrelSweepHit(player, newProj); victim != nil {
+				if newProj.explosive() {
+					projectilesToRemove = append(projectilesToRemove, game.detonate(newProj)...)
+				} else {
+					game.applyProjectileHit(newProj, victim)
+				}
+				continue
+			}
+			game.Projectiles[projID] = newProj
+		}
+		timer.lap(PhaseShooting)
+	}
+	game.resolveTankCollisions()
+	game.dropIntoPits()
+	timer.lap(PhaseMovement)
+
+	// Обновляем снаряды и проверяем коллизии
+	physics := game.Map.Physics
+	game.grid.rebuild(game.Players) // Игроки уже на своих местах в этом тике (см. spatialgrid.go)
+	for id, proj := range game.Projectiles {
+		if proj.Exploded {
+			continue // Подорван цепным взрывом раньше в этом тике
+		}
+
+		// Физика карты: гравитация и трение
+		proj.VY += physics.Gravity * dt
+		if physics.Friction > 0 {
+			damping := math.Max(0, 1-physics.Friction*dt)
+			proj.VX *= damping
+			proj.VY *= damping
+		}
+
+		proj.X += proj.VX * dt
+		proj.Y += proj.VY * dt
+		proj.Traveled += math.Hypot(proj.VX, proj.VY) * dt
+
+		// Снаряд, летящий дольше или дальше лимита, исчезает и на арене
+		if game.projectileExpired(proj) {
+			projectilesToRemove = append(projectilesToRemove, id)
+			continue
+		}
+
+		// Удаление за границами (на "закольцованной" карте снаряд переносится, но ограниченное число раз)
+		if !game.Arena.Contains(proj.X, proj.Y) {
+			if physics.EdgeMode != EdgeWrap || proj.Wraps >= MaxProjectileWraps {
+				projectilesToRemove = append(projectilesToRemove, id)
+				if owner, ok := game.Players[proj.OwnerID]; ok && config.BorderMisses {
+					owner.BorderMisses++
+				}
+				continue
+			}
+			proj.Wraps++
+			proj.X = wrapCoord(proj.X, float64(game.Bounds.Width))
+			proj.Y = wrapCoord(proj.Y, float64(game.Bounds.Height))
+		}
+		timer.lap(PhaseProjectiles)
+
+		// Проверка столкновения со стенами
+		if wall := game.wallHitBy(proj); wall != nil {
+			projectilesToRemove = append(projectilesToRemove, id)
+			if proj.explosive() {
+				projectilesToRemove = append(projectilesToRemove, game.detonate(proj)...)
+			}
+			if wall.Destructible() {
+				wall.Health -= proj.Damage
+				wallsChanged = true
+				if wall.Health <= 0 {
+					wall.Destroyed = true
+					game.emit(GameEvent{Kind: EventWallDestroyed, ProjectileID: id, Detail: wall.ID})
+				}
+			}
+			continue
+		}
+
+		// Проверка столкновения с игроками (только с ближайшими по сетке)
+		for _, player := range game.grid.candidates(proj.X, proj.Y, proj.CollisionRadius) {
+			if !game.canHit(proj, player) {
+				continue
+			}
+
+			distSq := math.Pow(proj.X-player.X, 2) + math.Pow(proj.Y-player.Y, 2)
+			radiiSq := math.Pow(player.Radius+proj.CollisionRadius, 2)
+
+			if distSq < radiiSq {
+				projectilesToRemove = append(projectilesToRemove, id) // Удаляем снаряд
+				if proj.explosive() {
+					projectilesToRemove = append(projectilesToRemove, game.detonate(proj)...) // Взрыв задевает и цель
+				} else {
+					game.applyProjectileHit(proj, player)
+				}
+				// TODO: Можно добавить эффект для игрока, в которого попали (например, респаун)
+				break // Снаряд может попасть только в одного игрока за тик
+			}
+		}
+		timer.lap(PhaseCollision)
+	}
+
+	if config.ProjectileCollisions {
+		projectilesToRemove = append(projectilesToRemove, game.interceptProjectiles(projectilesToRemove)...)
+		timer.lap(PhaseCollision)
+	}
+
+	// Удаляем помеченные снаряды
+	for _, id := range projectilesToRemove {
+		if _, ok := game.Projectiles[id]; ok {
+			delete(game.Projectiles, id)
+			game.emit(GameEvent{Kind: EventProjectileRemoved, ProjectileID: id})
+		}
+	}
+
+	game.sanitizeEntities()
+	game.rebalanceTeams()
+	game.checkRoundOver()
+
+	// Убираем разрушенные стены и сообщаем клиентам новое состояние карты
+	if wallsChanged {
+		remaining := game.Walls[:0]
+		for _, wall := range game.Walls {
+			if wall.Destroyed {
+				continue
+			}
+			remaining = append(remaining, wall)
+		}
+		game.Walls = remaining
+		game.emitMessage(GameEvent{Kind: EventWallsChanged}, "", ServerMessage{Type: "walls", Payload: game.Walls})
+	}
+	return game.takeEvents()
+}
+
+// isFinite сообщает, что число не NaN и не бесконечность
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// sanitizeEntities ищет объекты с NaN/Inf в координатах: игроков переставляет в безопасную точку,
+// снаряды удаляет. Без этого NaN расползается по проверкам столкновений и ломает JSON для клиентов.
+// Вызывается под game.mutex.
+func (game *GameState) sanitizeEntities() {
+	for _, p := range game.Players {
+		if !isFinite(p.X) || !isFinite(p.Y) {
+			slog.Warn("Некорректная позиция игрока, игрок перемещён", "player_id", p.ID, "x", p.X, "y", p.Y)
+			p.X, p.Y = game.chooseSpawn(p)
+			nonFiniteEntitiesTotal.WithLabelValues("player").Inc()
+		}
+		if !isFinite(p.AimAngle) || !isFinite(p.DesiredAimAngle) || !isFinite(p.BodyAngle) {
+			slog.Warn("Некорректный угол игрока, угол сброшен", "player_id", p.ID)
+			setAim(p, 0)
+			p.BodyAngle = 0
+			p.Input.AimX, p.Input.AimY = 0, 0
+			p.Input.AimAngle, p.Input.AimByAngle = 0, false
+			nonFiniteEntitiesTotal.WithLabelValues("player").Inc()
+		}
+	}
+	for id, proj := range game.Projectiles {
+		if !isFinite(proj.X) || !isFinite(proj.Y) || !isFinite(proj.VX) || !isFinite(proj.VY) {
+			slog.Warn("Некорректное состояние снаряда, снаряд удалён", "projectile_id", id)
+			delete(game.Projectiles, id)
+			nonFiniteEntitiesTotal.WithLabelValues("projectile").Inc()
+		}
+	}
+}
+
+// canHit сообщает, может ли снаряд вообще попасть в игрока (не в своего владельца и с подходящей маской)
+func (game *GameState) canHit(proj *Projectile, player *Player) bool {
+	return proj.OwnerID != player.ID && collides(proj.Mask, player.Layer) && solid(player) &&
+		(config.FriendlyFire || !game.friendlyProjectile(proj, player))
+}
+
+// projectileExpired сообщает, отлетал ли снаряд своё по config.ProjectileLifetime или
+// config.ProjectileRange. Вызывается под game.mutex.
+func (game *GameState) projectileExpired(proj *Projectile) bool {
+	if config.ProjectileLifetime > 0 && game.gameNow().Sub(proj.SpawnTime) >= config.ProjectileLifetime {
+		return true
+	}
+	return config.ProjectileRange > 0 && proj.Traveled >= config.ProjectileRange
+}
+
+// solid сообщает, есть ли у танка корпус для столкновений. Подбитый танк, ждущий возрождения, -
+// только обломки: снаряды и взрывы проходят сквозь него. Отключившийся танк по умолчанию
+// остаётся целью только на время config.DisconnectLinger (см. config.HitLingering); дальше,
+// пока он ждёт переподключения, он неуязвим - иначе неподвижный танк был бы лёгкой добычей.
+func solid(player *Player) bool {
+	if player.Dead {
+		return false
+	}
+	if !player.Disconnected {
+		return true
+	}
+	return config.HitLingering && time.Now().Before(player.LingerHitUntil)
+}
+
+// applyProjectileHit наносит урон игроку victim снарядом proj и начисляет очки владельцу снаряда.
+// Удалять снаряд должен вызывающий. Вызывается под game.mutex.
+func (game *GameState) applyProjectileHit(proj *Projectile, victim *Player) {
+	// Неуязвимый после появления танк поглощает снаряд без урона
+	if game.gameNow().Before(victim.InvulUntil) {
+		return
+	}
+
+	// Уменьшаем жизни игрока
+	killed := victim.Lives > 0 && victim.Lives <= proj.Damage // Этот снаряд добивает игрока
+	victim.Lives -= proj.Damage
+	victim.Engaged = true
+	game.scoreboardDirty = true
+	credited := creditedOwners(proj, victim.ID)
+	creditedID := proj.OwnerID
+	if len(credited) > 0 {
+		creditedID = credited[0]
+	}
+	hit := GameEvent{Kind: EventHit, PlayerID: creditedID, TargetID: victim.ID, ProjectileID: proj.ID, X: proj.X, Y: proj.Y}
+	if config.DamageIndicators {
+		game.emitMessage(hit, victim.ID, ServerMessage{Type: "damageDirection", Payload: DamageDirectionPayload{
+			Angle:      math.Atan2(proj.OriginY-victim.Y, proj.OriginX-victim.X),
+			Damage:     proj.Damage,
+			AttackerID: proj.OwnerID,
+		}})
+	} else {
+		game.emit(hit)
+	}
+	game.emitSound(hit)
+	hitsTotal.Inc()
+	if killed {
+		game.emitDeath(GameEvent{Kind: EventKill, PlayerID: creditedID, TargetID: victim.ID, ProjectileID: proj.ID, X: victim.X, Y: victim.Y})
+		victim.Deaths++
+		victim.Streak = 0
+		game.killPlayer(victim, creditedID)
+		if config.DespawnOnDeath {
+			if n := game.removeProjectilesOf(victim.ID); n > 0 {
+				slog.Debug("Убраны снаряды погибшего игрока", "player_id", victim.ID, "projectiles", n)
+			}
+		}
+	}
+
+	// Точность считается тому, кто выпустил снаряд
+	if shooter, ok := game.Players[originalOwner(proj)]; ok && shooter.ID != victim.ID {
+		shooter.ShotsHit++
+	}
+
+	// Очки и убийство - по политике начисления. Попадание в союзника (при включённом огне по своим) очков не даёт.
+	for _, id := range credited {
+		owner, ok := game.Players[id]
+		if !ok || teammates(owner, victim) {
+			continue
+		}
+		owner.Score++
+		if killed {
+			owner.Kills++
+			owner.Streak++
+			grantKillLives(owner)
+		}
+	}
+}
+
+// grantKillLives добавляет убийце config.LivesPerKill жизней, не поднимая их выше лимита.
+// Погибший к этому моменту стрелок жизни не получает. Вызывается под game.mutex.
+func grantKillLives(shooter *Player) {
+	if config.LivesPerKill <= 0 || shooter.Lives <= 0 {
+		return
+	}
+	limit := config.MaxLives
+	if limit <= 0 {
+		limit = tankClasses[shooter.Class].Lives // Без явного лимита - не больше стартовых жизней класса
+	}
+	if shooter.Lives >= limit {
+		return
+	}
+	shooter.Lives = min(limit, shooter.Lives+config.LivesPerKill)
+	slog.Debug("Игрок получает жизни за убийство", "player_id", shooter.ID, "lives", shooter.Lives)
+}
+
+// barrelSweepHit проверяет отрезок от центра стрелка до дула (где появился снаряд) и возвращает
+// ближайшего к стрелку противника на этом отрезке, или nil. Вызывается под game.mutex.
+func (game *GameState) barrelSweepHit(shooter *Player, proj *Projectile) *Player {
+	var hit *Player
+	bestT := math.Inf(1)
+	for _, player := range game.Players {
+		if !game.canHit(proj, player) {
+			continue
+		}
+		dist, t := segmentPointDistance(shooter.X, shooter.Y, proj.X, proj.Y, player.X, player.Y)
+		if dist < player.Radius+proj.CollisionRadius && t < bestT {
+			hit, bestT = player, t
+		}
+	}
+	return hit
+}
+
+// segmentPointDistance возвращает расстояние от точки (px, py) до отрезка (ax, ay)-(bx, by)
+// и положение ближайшей точки на отрезке t в диапазоне [0, 1]
+func segmentPointDistance(ax, ay, bx, by, px, py float64) (float64, float64) {
+	dx, dy := bx-ax, by-ay
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/lengthSq))
+	}
+	return math.Hypot(ax+t*dx-px, ay+t*dy-py), t
+}
+
+// broadcastLoop - рассылает состояние игры клиентам (до закрытия stop)
+func (game *GameState) broadcastLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second / time.Duration(config.BroadcastRate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		broadcastLoopStarted.Store(true)
+		game.sendGameStateToAll()
+		game.sendScoreboardIfNeeded()
+	}
+}
+
+// sendScoreboardIfNeeded - рассылает таблицу очков, если она изменилась
+// или пришло время очередного полного кадра
+func (game *GameState) sendScoreboardIfNeeded() {
+	game.mutex.Lock() // Полная блокировка: сбрасываем флаг изменений
+	defer game.mutex.Unlock()
+
+	if !game.scoreboardDirty && time.Since(game.scoreboardSentTime) < ScoreboardKeyframeInterval {
+		return
+	}
+	if game.scoreboardDirty {
+		game.scoreboardVersion++
+		game.scoreboardDirty = false
+	}
+	game.scoreboardSentTime = time.Now()
+
+	entries := make([]ScoreboardEntry, 0, len(game.Players))
+	for _, p := range game.Players {
+		entries = append(entries, ScoreboardEntry{ID: p.ID, Nickname: p.Nickname, Score: p.Score, Lives: p.Lives, Kills: p.Kills, Deaths: p.Deaths, Team: p.Team, Color: scoreboardColor(p)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	game.broadcastMessage(ServerMessage{Type: "scoreboard", Payload: ScoreboardPayload{Version: game.scoreboardVersion, Entries: entries}})
+}
+
+// sendGameStateToAll - готовит и отправляет состояние всем
+func (game *GameState) sendGameStateToAll() {
+	game.mutex.RLock() // Блокировка чтения - другие читатели не блокируются
+	defer game.mutex.RUnlock()
+
+	// Создаем срезы для JSON (карты не гарантируют порядок в JSON)
+	playerList := make([]*Player, 0, len(game.Players))
+	for _, p := range game.Players {
+		playerList = append(playerList, p)
+	}
+	projectileList := make([]*Projectile, 0, len(game.Projectiles))
+	for _, p := range game.Projectiles {
+		projectileList = append(projectileList, p)
+	}
+
+	payload := GameStatePayload{
+		Players:       playerList,
+		Projectiles:   projectileList,
+		Phase:         game.Phase,
+		PhaseEndsInMs: game.phaseRemainingMs(),
+		NoFireMs:      max(0, game.NoFireUntil.Sub(game.gameNow()).Milliseconds()),
+		TeamScores:    game.teamScores(),
+		Events:        game.takeFeed(),
+		RadarBlips:    radarBlips(playerList),
+		ServerTime:    serverTimeMs(game.tickTime),
+		TickMs:        tickIntervalMs(),
+	}
+	var enc encodedEntities
+	if config.DeltaState {
+		// Объекты сериализуются по отдельности один раз, чтобы сравнить их с отправленными каждому игроку
+		game.stateSeq++
+		payload.Seq = game.stateSeq
+		var err error
+		if enc, err = encodeEntities(playerList, projectileList); err != nil {
+			slog.Error("Ошибка маршалинга объектов состояния", "err", err)
+			return
+		}
+	}
+	msg := ServerMessage{Type: "gameState", Payload: payload}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("Ошибка маршалинга gameState", "err", err)
+		return
+	}
+
+	if game.highlights != nil {
+		game.highlights.record(msgBytes)
+	}
+	messages, err := game.chunkGameState(payload, msgBytes)
+	if err != nil {
+		slog.Error("Ошибка маршалинга gameStateChunk", "err", err)
+		return
+	}
+
+	// Отправляем сообщение в канал каждого игрока
+	now := time.Now()
+	var binaryState []byte // Кодируется при первом клиенте с двоичным форматом
+	for _, player := range game.Players {
+		if player.MessageChan == nil {
+			continue // Ботам и отключившимся отправлять некуда
+		}
+		if player.UpdateInterval > 0 {
+			// Клиент просил присылать состояние реже - пропускаем рассылки до его очередного срока.
+			// Под RLock поле меняет только эта горутина (остальные - под полной блокировкой), поэтому запись безопасна.
+			if now.Before(player.NextStateSend) {
+				continue
+			}
+			// Запас в полпериода рассылки, чтобы дрожание тикера не съедало целую рассылку
+			player.NextStateSend = now.Add(player.UpdateInterval - time.Second/time.Duration(config.BroadcastRate)/2)
+		}
+		visiblePlayers, visible := game.cullView(player, playerList, projectileList)
+		if player.MaxProjectiles > 0 && len(visible) > player.MaxProjectiles {
+			visible = nearestProjectiles(player, visible, player.MaxProjectiles)
+		}
+		// Игроку отсекли часть объектов (радиус видимости или лимит снарядов) - ему отдельное сообщение
+		culled := len(visiblePlayers) < len(playerList) || len(visible) < len(projectileList)
+		personal := payload
+		personal.Players = visiblePlayers
+		personal.Projectiles = visible
+		if player.BinaryState {
+			if culled {
+				queueMessage(player, encodeBinaryState(personal))
+				continue
+			}
+			if binaryState == nil {
+				binaryState = encodeBinaryState(payload)
+			}
+			queueMessage(player, binaryState)
+			continue
+		}
+		if config.DeltaState {
+			if !needsFullState(player) {
+				game.sendStateDelta(player, personal, enc, visible)
+				continue
+			}
+			game.rememberFullState(player, enc, visiblePlayers, visible)
+		}
+		if culled {
+			personalMessages, err := game.gameStateMessages(personal)
+			if err != nil {
+				slog.Error("Ошибка маршалинга gameState", "err", err)
+				continue
+			}
+			for _, m := range personalMessages {
+				queueMessage(player, m)
+			}
+			continue
+		}
+		for _, m := range messages {
+			queueMessage(player, m)
+		}
+	}
+	for _, m := range messages {
+		game.queueSpectators(m) // Зрителям - полное состояние без отсечения
+	}
+}
+
+// setUpdateRate задаёт частоту, с которой клиент получает состояние игры.
+// Значение ограничивается диапазоном [MinUpdateRate, config.BroadcastRate]. Вызывается под game.mutex.
+func setUpdateRate(player *Player, rate int) {
+	rate = max(MinUpdateRate, min(config.BroadcastRate, rate))
+	player.UpdateInterval = 0
+	if rate < config.BroadcastRate {
+		player.UpdateInterval = time.Second / time.Duration(rate)
+	}
+	player.NextStateSend = time.Time{} // Новая частота действует сразу
+}
+
+// nearestProjectiles возвращает не более n снарядов, ближайших к игроку
+func nearestProjectiles(player *Player, projectiles []*Projectile, n int) []*Projectile {
+	sorted := append([]*Projectile(nil), projectiles...)
+	sort.Slice(sorted, func(i, j int) bool {
+		di := math.Hypot(sorted[i].X-player.X, sorted[i].Y-player.Y)
+		dj := math.Hypot(sorted[j].X-player.X, sorted[j].Y-player.Y)
+		return di < dj
+	})
+	return sorted[:n]
+}
+
+// --- Обработка WebSocket ---
+
+// handleConnections - обрабатывает новые подключения
+func handleConnections(w http.ResponseWriter, r *http.Request) {
+	roomID := r.URL.Query().Get("room") // Комната: /ws?room=abc (без параметра - комната по умолчанию)
+	if roomID == "" {
+		roomID = DefaultRoomID
+	}
+	if err := validRoomID(roomID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	encoding := r.URL.Query().Get("encoding") // Формат состояния: /ws?encoding=binary (по умолчанию JSON)
+	if encoding != "" && encoding != EncodingJSON && encoding != EncodingBinary {
+		http.Error(w, fmt.Sprintf("неизвестная кодировка %q: допустимы %s и %s", encoding, EncodingJSON, EncodingBinary), http.StatusBadRequest)
+		return
+	}
+	if !acquireConnSlot() {
+		rejectConnection(w, r)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("Ошибка обновления до WebSocket", "err", err)
+		releaseConnSlot()
+		return
+	}
+	configureCompression(conn) // До запуска writer: уровень нельзя менять параллельно с записью
+
+	room := rooms.join(roomID)
+	if room == nil {
+		connectionsRejectedTotal.Inc()
+		slog.Warn("Соединение отклонено: достигнут лимит комнат", "remote_addr", conn.RemoteAddr().String(), "limit", config.MaxRooms)
+		msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too many rooms, try again later")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(connRejectWriteTimeout))
+		conn.Close()
+		releaseConnSlot()
+		return
+	}
+	game := room.Game
+	slog.Info("Новое WebSocket соединение", "remote_addr", conn.RemoteAddr().String(), "room", room.ID)
+
+	game.mutex.Lock() // Блокируем для записи
+	if player := game.sessionPlayer(r.URL.Query().Get("token")); player != nil {
+		if player.MessageChan != nil && config.SessionConflict == SessionReject {
+			game.mutex.Unlock()
+			slog.Warn("Соединение отклонено: сессия занята другим соединением", "player_id", player.ID, "remote_addr", conn.RemoteAddr().String())
+			rejectSessionInUse(conn)
+			rooms.leave(room)
+			releaseConnSlot()
+			return
+		}
+		// Переподключение: продолжаем тем же игроком (см. session.go)
+		resumeSession(player, conn)
+		player.BinaryState = encoding == EncodingBinary // Формат - по новому соединению
+		player.IP, player.ConnectedAt = clientIP(r), time.Now()
+		slog.Info("Игрок переподключился", "player_id", player.ID, "remote_addr", conn.RemoteAddr().String())
+		game.startConnection(room, player)
+		game.mutex.Unlock()
+		return
+	}
+
+	if r.URL.Query().Get("spectate") == "1" {
+		// Зритель не занимает места в комнате (см. spectators.go)
+		game.addSpectator(room, conn, clientIP(r), encoding == EncodingBinary)
+		game.mutex.Unlock()
+		return
+	}
+
+	if game.roomFull() {
+		game.mutex.Unlock()
+		slog.Warn("Соединение отклонено: комната заполнена", "remote_addr", conn.RemoteAddr().String(), "room", room.ID, "limit", config.MaxPlayers)
+		rejectFullRoom(conn)
+		rooms.leave(room)
+		releaseConnSlot()
+		return
+	}
+
+	player := game.addPlayer(conn, clientIP(r), encoding == EncodingBinary)
+	if rate := r.URL.Query().Get("rate"); rate != "" {
+		// Частоту обновлений можно задать сразу при подключении: /ws?rate=10
+		if n, err := strconv.Atoi(rate); err == nil {
+			setUpdateRate(player, n)
+		} else {
+			slog.Warn("Некорректный параметр rate", "rate", rate, "remote_addr", conn.RemoteAddr().String())
+		}
+	}
+	game.startConnection(room, player)
+	game.mutex.Unlock()
+}
+
+// addPlayer создаёт игрока для соединения conn и выводит его танк на арену. Вызывается под game.mutex.
+func (game *GameState) addPlayer(conn *websocket.Conn, ip string, binaryState bool) *Player {
+	playerID := generateID("plr", &nextPlayerID)
+	player := &Player{
+		ID:           playerID,
+		Class:        DefaultTankClass,
+		Team:         game.assignTeam(),
+		Color:        randomColor(),
+		Score:        0,
+		Conn:         conn,
+		MessageChan:  make(chan []byte, 32), // Буферизованный канал
+		LastShotTime: time.Time{},           // Нулевое время - можно стрелять сразу
+		Nickname:     "Player " + playerID,  // Дефолтное имя
+		Layer:        LayerPlayer,
+		SessionToken: newSessionToken(),
+		BinaryState:  binaryState,
+		IP:           ip,
+		ConnectedAt:  time.Now(),
+	}
+	game.spawnPlayer(player) // устанавливаем размер, скорость, начальное колво жизней и позицию подальше от противников
+	applyWeapon(player, weapons[DefaultWeapon])
+	game.Players[playerID] = player
+	game.scoreboardDirty = true
+	slog.Info("Создан игрок", "player_id", playerID, "remote_addr", conn.RemoteAddr().String())
+	return player
+}
+
+// startConnection отправляет клиенту ID, токен сессии, карту и стены и запускает reader и writer
+// для текущего соединения игрока. Вызывается под game.mutex: канал сообщений может заменить
+// только переподключение под той же блокировкой.
+func (game *GameState) startConnection(room *Room, player *Player) {
+	conn, messageChan := player.Conn, player.MessageChan
+	assignBytes, _ := json.Marshal(ServerMessage{Type: "assignId", Payload: map[string]string{"id": player.ID, "token": player.SessionToken}})
+	mapBytes, _ := json.Marshal(ServerMessage{Type: "map", Payload: game.Map})
+	wallsBytes, _ := json.Marshal(ServerMessage{Type: "walls", Payload: game.Walls})
+	for _, b := range [][]byte{assignBytes, mapBytes, wallsBytes} {
+		select {
+		case messageChan <- b:
+		default:
+		}
+	}
+
+	// Запускаем горутины для чтения и записи для этого клиента
+	go game.writer(player, conn, messageChan)
+	go func() {
+		// Пока игрок ждёт переподключения, комната не удаляется
+		if linger := game.reader(player, conn); linger > 0 {
+			time.AfterFunc(linger, func() { rooms.leave(room) })
+			return
+		}
+		rooms.leave(room) // После удаления игрока: опустевшая комната останавливается
+	}()
+}
+
+// reader - читает сообщения от клиента через conn. Возвращает, сколько отключившийся
+// танк ещё останется на арене (0 - игрок удалён или перехвачен новым соединением).
+func (game *GameState) reader(player *Player, conn *websocket.Conn) (linger time.Duration) {
+	playerID := player.ID
+	reason := DisconnectReadError // Уточняется по ошибке чтения
+
+	defer func() {
+		game.mutex.Lock()
+		if player.Conn != conn {
+			// Игрок уже переподключился новым соединением - закрываем только старое
+			slog.Debug("Reader завершается для старого соединения", "player_id", playerID, "remote_addr", conn.RemoteAddr().String())
+			conn.Close()
+			releaseConnSlot()
+			game.mutex.Unlock()
+			return
+		}
+		// Причина, выставленная раньше (например, ошибка записи), важнее ошибки чтения, которую она вызвала
+		if player.DisconnectReason == "" {
+			player.DisconnectReason = reason
+		}
+		disconnectsTotal.WithLabelValues(string(player.DisconnectReason)).Inc()
+		nickname, score := player.Nickname, player.Score // Итоговый счёт для таблицы рекордов
+		slog.Info("Reader завершается", "player_id", playerID, "remote_addr", conn.RemoteAddr().String(), "reason", player.DisconnectReason)
+		close(player.MessageChan) // Закрываем канал записи
+		player.MessageChan = nil
+		conn.Close() // Закрываем соединение
+		releaseConnSlot()
+		if config.DespawnOnDisconnect {
+			// Снаряды ушедшего игрока не должны и дальше летать и поражать других
+			if n := game.removeProjectilesOf(playerID); n > 0 {
+				slog.Debug("Убраны снаряды отключившегося игрока", "player_id", playerID, "projectiles", n)
+			}
+		}
+		linger = max(config.DisconnectLinger, config.ReconnectGrace)
+		if player.DisconnectReason == DisconnectKicked {
+			linger = config.DisconnectLinger // Кикнутый игрок не переподключится
+		}
+		if linger > 0 {
+			// Танк остаётся на месте, пока клиенты плавно его убирают или пока игрок не переподключится
+			// с токеном сессии. Подбить его можно только первые config.DisconnectLinger (см. solid).
+			player.Disconnected = true
+			player.LingerUntil = time.Now().Add(linger)
+			player.LingerHitUntil = time.Now().Add(config.DisconnectLinger)
+			player.Input = PlayerInput{}
+			player.WantsToShoot = false
+			slog.Info("Игрок отключился, танк будет удалён позже", "player_id", playerID, "after", linger)
+		} else {
+			game.removePlayer(playerID) // Удаляем игрока из игры
+		}
+		game.mutex.Unlock()
+		leaderboard.record(nickname, score, time.Now()) // Запись в файл - уже без блокировки игры
+	}()
+
+	conn.SetReadLimit(config.ReadLimit)
+	// Соединение живо, пока от клиента приходят сообщения или ответы на ping из writer.
+	// Пропавший без закрытия TCP клиент отключается по истечении срока чтения.
+	conn.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	})
+	limiter := newMessageLimiter(config.MessageRate, config.MessageBurst) // Только этот reader, см. ratelimit.go
+	chatLimiter := newMessageLimiter(ChatRate, ChatBurst)                 // Отдельно для чата, см. chat.go
+
+	for {
+		messageType, message, err := conn.ReadMessage()
+		receivedAt := time.Now()
+		if err != nil {
+			reason = readDisconnectReason(err)
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				slog.Warn("Неожиданная ошибка чтения", "player_id", playerID, "err", err)
+			} else {
+				slog.Info("Соединение закрыто", "player_id", playerID, "err", err)
+			}
+			break
+		}
+		conn.SetReadDeadline(receivedAt.Add(config.PongTimeout))
+
+		messagesReceivedTotal.Inc()
+		if !limiter.allow(receivedAt) {
+			messagesDroppedTotal.Inc()
+			if n := limiter.takeDropped(receivedAt); n > 0 {
+				slog.Warn("Игрок превышает лимит сообщений", "player_id", playerID, "rate", config.MessageRate, "dropped", n)
+			}
+			if config.FloodKick > 0 && limiter.flooding(receivedAt) >= config.FloodKick {
+				slog.Warn("Игрок отключён за превышение лимита сообщений", "player_id", playerID, "after", config.FloodKick)
+				reason = DisconnectFlood
+				msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many messages")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(connRejectWriteTimeout))
+				break
+			}
+			continue
+		}
+
+		if messageType == websocket.BinaryMessage {
+			// Компактный двоичный ввод (см. binary.go)
+			in, err := decodeBinaryInput(message)
+			if err != nil {
+				slog.Warn("Ошибка разбора двоичного ввода", "player_id", playerID, "err", err)
+				continue
+			}
+			game.mutex.Lock()
+			if p, ok := game.Players[playerID]; ok {
+				if err := game.applyBinaryInput(p, in); err != nil {
+					slog.Warn("Некорректный двоичный ввод отброшен", "player_id", playerID, "err", err)
+					invalidPayloadsTotal.WithLabelValues("input").Inc()
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "invalid_payload", Message: "non-finite input values", Action: "input"}})
+				}
+			}
+			game.mutex.Unlock()
+			continue
+		}
+		if messageType != websocket.TextMessage {
+			slog.Warn("Получено не текстовое сообщение", "player_id", playerID)
+			continue
+		}
+
+		var msg ClientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			slog.Warn("Ошибка парсинга JSON", "player_id", playerID, "err", err)
+			continue
+		}
+
+		// Обновляем состояние игрока (ввод/стрельба)
+		game.mutex.Lock()
+		if p, ok := game.Players[playerID]; ok {
+			switch msg.Action {
+			case "setNickname":
+				var nicknamePayload struct {
+					Nickname string `json:"nickname"`
+				}
+				if err := json.Unmarshal(msg.Payload, &nicknamePayload); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "setNickname", "err", err)
+					break
+				}
+				nickname, code := sanitizeNickname(nicknamePayload.Nickname)
+				if code == "" && config.UniqueNicknames && game.nicknameTaken(nickname, playerID) {
+					code = NicknameTaken
+				}
+				if code != "" {
+					slog.Info("Никнейм отклонён", "player_id", playerID, "nickname", nicknamePayload.Nickname, "reason", code)
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: code, Message: "nickname rejected: " + code, Action: msg.Action}})
+					break
+				}
+				p.Nickname = nickname
+				game.scoreboardDirty = true
+				slog.Info("Игрок установил никнейм", "player_id", playerID, "nickname", p.Nickname)
+			case "selectClass":
+				var classPayload struct {
+					Class string `json:"class"`
+				}
+				if err := json.Unmarshal(msg.Payload, &classPayload); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "selectClass", "err", err)
+					break
+				}
+				class, ok := tankClasses[classPayload.Class]
+				if !ok {
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "unknown_class", Message: "unknown tank class: " + classPayload.Class, Action: msg.Action}})
+					break
+				}
+				if p.Engaged && game.Phase == PhasePlaying {
+					// Игрок уже в бою - класс сменится при следующем появлении
+					p.PendingClass = class.Name
+					slog.Info("Игрок выбрал класс (применится при появлении)", "player_id", playerID, "class", class.Name)
+				} else {
+					applyTankClass(p, class)
+					game.keepInArena(p) // Новый радиус может не помещаться у края арены
+					game.scoreboardDirty = true
+					slog.Info("Игрок выбрал класс", "player_id", playerID, "class", class.Name)
+				}
+			case "resync":
+				// Клиент пропустил изменения состояния - в следующей рассылке он получит полное
+				if p.delta != nil {
+					p.delta.resync = true
+				}
+			case "resetInput":
+				// Клиент мог потерять отпускание клавиши (смена схемы управления, потеря фокуса) -
+				// сбрасываем ввод, чтобы танк не ехал дальше сам по себе
+				p.Input = PlayerInput{}
+				p.WantsToShoot = false
+				p.ShotQueuedAt = time.Time{}
+			case "respawn":
+				if p.Dead {
+					p.RespawnRequested = true // Возрождение произойдёт в игровом цикле, когда истечёт задержка
+				}
+			case "selectWeapon":
+				var weaponPayload struct {
+					Weapon string `json:"weapon"`
+				}
+				if err := json.Unmarshal(msg.Payload, &weaponPayload); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "selectWeapon", "err", err)
+					break
+				}
+				weapon, ok := weapons[weaponPayload.Weapon]
+				if !ok {
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "unknown_weapon", Message: "unknown weapon: " + weaponPayload.Weapon, Action: msg.Action}})
+					break
+				}
+				applyWeapon(p, weapon)
+				slog.Info("Игрок выбрал оружие", "player_id", playerID, "weapon", weapon.Name)
+			case "timeSync", "ping":
+				var syncPayload struct {
+					ClientTime float64 `json:"clientTime"`
+				}
+				if err := json.Unmarshal(msg.Payload, &syncPayload); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "timeSync", "err", err)
+					break
+				}
+				sendToPlayer(p, ServerMessage{Type: "timeSync", Payload: TimeSyncPayload{
+					ClientTime:        syncPayload.ClientTime,
+					ServerReceiveTime: receivedAt.UnixMilli(),
+					ServerSendTime:    time.Now().UnixMilli(),
+				}})
+			case "settings":
+				var settings ClientSettings
+				if err := json.Unmarshal(msg.Payload, &settings); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "settings", "err", err)
+					break
+				}
+				if settings.MaxProjectiles != nil {
+					p.MaxProjectiles = max(0, *settings.MaxProjectiles)
+				}
+				if settings.UpdateRate != nil {
+					setUpdateRate(p, *settings.UpdateRate)
+				}
+				if settings.ManualRespawn != nil {
+					p.ManualRespawn = *settings.ManualRespawn
+				}
+				if settings.ScoreboardColor != nil {
+					if c := *settings.ScoreboardColor; c == "" || validColor(c) {
+						p.ScoreboardColor = c
+						game.scoreboardDirty = true
+					} else {
+						sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "invalid_color", Message: "scoreboard color must be #rrggbb: " + c, Action: msg.Action}})
+					}
+				}
+			case "input":
+				var inputPayload PlayerInput
+				if err := json.Unmarshal(msg.Payload, &inputPayload); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "input", "err", err)
+					invalidPayloadsTotal.WithLabelValues("input").Inc()
+					break
+				}
+				if err := validateInput(inputPayload); err != nil {
+					slog.Warn("Некорректный ввод отброшен", "player_id", playerID, "action", "input", "err", err)
+					invalidPayloadsTotal.WithLabelValues("input").Inc()
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "invalid_payload", Message: "non-finite input values", Action: msg.Action}})
+					break
+				}
+				if inputPayload.Seq != 0 {
+					if !seqAfter(inputPayload.Seq, p.InputSeq) {
+						break // Опоздавший или повторный ввод
+					}
+					p.InputSeq = inputPayload.Seq
+				}
+				game.clampInput(&inputPayload)
+				p.Input = inputPayload // Угол башни по точке прицела считает игровой цикл (updateAim)
+			case "shoot":
+				// Парсим команду выстрела с координатами прицела
+				var shootCmd ShootCommand
+				if err := json.Unmarshal(msg.Payload, &shootCmd); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "shoot", "err", err)
+					invalidPayloadsTotal.WithLabelValues("shoot").Inc()
+					break
+				}
+				if err := validateShoot(shootCmd); err != nil {
+					slog.Warn("Некорректный выстрел отброшен", "player_id", playerID, "action", "shoot", "err", err)
+					invalidPayloadsTotal.WithLabelValues("shoot").Inc()
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "invalid_payload", Message: "non-finite shoot values", Action: msg.Action}})
+					break
+				}
+				// Обновляем только желаемый угол башни; выстрел уйдёт, когда она довернёт
+				p.DesiredAimAngle = math.Atan2(shootCmd.DirectionY, shootCmd.DirectionX)
+				p.WantsToShoot = true
+				p.ShotSeq = shootCmd.Seq
+				p.ShotCharge = clampCharge(shootCmd.Charge)
+				game.sendShootResult(p)
+			case "chat":
+				var chatCmd ChatCommand
+				if err := json.Unmarshal(msg.Payload, &chatCmd); err != nil {
+					slog.Warn("Ошибка парсинга payload", "player_id", playerID, "action", "chat", "err", err)
+					break
+				}
+				if !chatLimiter.allow(receivedAt) {
+					sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "chat_rate_limited", Message: "too many chat messages", Action: msg.Action}})
+					break
+				}
+				game.chat(p, chatCmd)
+			default:
+				slog.Warn("Неизвестное действие", "player_id", playerID, "action", msg.Action)
+				unknownActionsTotal.Inc()
+				sendToPlayer(p, ServerMessage{Type: "error", Payload: ErrorPayload{Code: "unknown_action", Message: "unknown action", Action: msg.Action}})
+			}
+		}
+		game.mutex.Unlock()
+	}
+	return // linger выставляет отложенная очистка
+}
+
+// writer - пишет сообщения из канала игрока в WebSocket соединение
+func (game *GameState) writer(player *Player, conn *websocket.Conn, messageChan chan []byte) {
+	playerID := player.ID
+
+	defer func() {
+		slog.Debug("Writer завершается", "player_id", playerID, "remote_addr", conn.RemoteAddr().String())
+	}()
+
+	ping := time.NewTicker(config.PingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case message, ok := <-messageChan:
+			if !ok {
+				return // Канал закрыт в reader
+			}
+			frameType := websocket.TextMessage
+			if isBinaryFrame(message) {
+				frameType = websocket.BinaryMessage // Двоичное состояние игры
+			}
+			err = conn.WriteMessage(frameType, message)
+			if err == nil {
+				messagesSentTotal.Inc()
+				messageBytesSentTotal.Add(float64(len(message)))
+			}
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(PingWriteTimeout))
+		}
+		if err != nil {
+			slog.Warn("Ошибка записи сообщения игроку", "player_id", playerID, "err", err)
+			game.mutex.Lock()
+			if player.Conn == conn { // Соединение не заменено переподключением
+				disconnectPlayer(player, DisconnectWriteError) // Разбудит reader, который выполнит очистку
+			}
+			game.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// --- Точка входа ---
+func main() {
+	config.registerFlags(flag.CommandLine)
+	if err := applyEnv(flag.CommandLine); err != nil {
+		fatal("Некорректная переменная окружения", err)
+	}
+	flag.Parse()
+
+	rand.Seed(time.Now().UnixNano())
+
+	if err := config.validate(); err != nil {
+		fatal("Некорректные настройки", err)
+	}
+	setupLogger()
+	config.applyConfig()
+	logConfig(flag.CommandLine)
+	initConnLimit(config.MaxConnections)
+	logSampleRates, _ = parseLogSampling(config.LogSample) // Уже проверено в validate
+	upgrader.ReadBufferSize = config.ReadBufferSize
+	upgrader.WriteBufferSize = config.WriteBufferSize
+	setupCompression()
+
+	startMap := defaultMap() // Карта, с которой начинается каждая комната
+	if config.MapPath != "" {
+		m, err := loadMap(config.MapPath)
+		if err != nil {
+			fatal("Ошибка загрузки карты", err)
+		}
+		startMap = m
+	}
+	if config.MapRotation != "" {
+		maps, err := loadMapRotation(config.MapRotation)
+		if err != nil {
+			fatal("Ошибка загрузки карт ротации", err)
+		}
+		mapRotation = maps
+		startMap = maps[0] // Ротация начинается с первой карты списка
+		slog.Info("Ротация карт", "order", config.RotationOrder, "maps", len(maps))
+	}
+	lb, err := loadLeaderboard(config.LeaderboardPath)
+	if err != nil {
+		fatal("Ошибка загрузки таблицы рекордов", err)
+	}
+	leaderboard = lb
+	rooms = newRoomManager(startMap)
+	game := rooms.defaultGame() // Сценарий и запись ярких моментов - только в комнате по умолчанию
+	if config.ScenarioPath != "" {
+		s, err := loadScenario(config.ScenarioPath, game.Map)
+		if err != nil {
+			fatal("Ошибка загрузки сценария", err)
+		}
+		game.applyScenario(s)
+	}
+	for range config.Bots {
+		game.spawnBot()
+	}
+	if config.HighlightsDir != "" {
+		recorder, err := newHighlightRecorder(config.HighlightsDir, config.HighlightBuffer)
+		if err != nil {
+			fatal("Ошибка подготовки каталога ярких моментов", err)
+		}
+		game.highlights = recorder
+		slog.Info("Запись ярких моментов включена", "dir", config.HighlightsDir, "buffer", config.HighlightBuffer)
+	}
+	slog.Info("Карта", "map", game.Map.Name, "width", game.Map.Width, "height", game.Map.Height, "physics", fmt.Sprintf("%+v", game.Map.Physics))
+
+	slog.Info("Запуск сервера Динамической Игры", "version", Version, "commit", Commit, "built", BuildTime)
+
+	if config.PprofAddr != "" {
+		startPprofServer(config.PprofAddr)
+	}
+
+	// Запускаем игровые циклы (ретранслятор своей игры не ведёт)
+	if config.RelayUpstream == "" {
+		rooms.startDefault()
+	} else {
+		slog.Info("Режим ретрансляции: соединения /ws передаются дальше", "upstream", config.RelayUpstream)
+	}
+
+	// Настройка HTTP сервера с обработкой статических файлов (собственный mux - см. profiling.go)
+	mux := http.NewServeMux()
+	fs := http.FileServer(http.Dir("./static"))              // Обслуживаем файлы из текущей директории
+	mux.Handle("/static/", http.StripPrefix("/static/", fs)) // Префикс для статических файлов
+
+	if config.RelayUpstream != "" {
+		mux.HandleFunc("/ws", handleRelay)
+	} else {
+		mux.HandleFunc("/ws", handleConnections)
+	}
+	mux.Handle("/metrics", metricsHandler())
+	mux.HandleFunc("GET /player/{id}", handlePlayerStats)
+	mux.HandleFunc("GET /version", handleVersion)
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.HandleFunc("GET /readyz", handleReadyz)
+	mux.HandleFunc("GET /leaderboard", handleLeaderboard)
+	if config.AdminToken != "" {
+		mux.HandleFunc("GET /admin", requireAdmin(handleAdminPlayers))
+		mux.HandleFunc("POST /admin/kick", requireAdmin(handleAdminKick))
+	}
+	if config.SnapshotEnabled {
+		mux.HandleFunc("/snapshot.png", handleSnapshot)
+	}
+	// новую ручку ктр будет выводить логин пользователя
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Проверяем существование файла
+		if r.URL.Path == "/" {
+
+			http.ServeFile(w, r, "index.html")
+			return
+		}
+
+		// Для всех остальных запросов пробуем найти файл
+		path := filepath.Join(".", r.URL.Path)
+		slog.Debug("Статический файл", "path", path)
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		http.ServeFile(w, r, path)
+	})
+
+	files, _ := filepath.Glob("*")
+	slog.Info("Сервер слушает", "addr", config.Addr, "files", files)
+
+	ln, err := net.Listen("tcp", config.Addr)
+	if err != nil {
+		fatal("Не удалось открыть адрес", err)
+	}
+	err = http.Serve(countingListener{ln}, mux)
+	if err != nil {
+		fatal("Критическая ошибка Serve", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Запуск: go test -race ./...
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil))) // Логи сервера тестам не нужны
+	os.Exit(m.Run())
+}
+
+// withConfig подменяет глобальный config на настройки по умолчанию с изменениями change
+// и восстанавливает прежний после теста
+func withConfig(t *testing.T, change func(c *Config)) {
+	t.Helper()
+	saved := config
+	config = defaultConfig()
+	if change != nil {
+		change(&config)
+	}
+	if err := config.validate(); err != nil {
+		t.Fatalf("некорректная конфигурация теста: %v", err)
+	}
+	config.applyConfig()
+	t.Cleanup(func() {
+		config = saved
+		config.applyConfig()
+	})
+}
+
+// startTestServer поднимает /ws на чистом менеджере комнат и возвращает адрес ws://.../ws.
+// Циклы комнаты по умолчанию не запускаются: тестам соединений они не нужны.
+// После теста (когда клиенты из dialTest уже закрыты) ждёт завершения reader и writer
+// соединений, иначе они читали бы config параллельно с его восстановлением.
+func startTestServer(t *testing.T) string {
+	t.Helper()
+	baseline := runtime.NumGoroutine()
+	rooms = newRoomManager(defaultMap())
+	connSlots = nil
+	initConnLimit(config.MaxConnections)
+	srv := httptest.NewServer(http.HandlerFunc(handleConnections))
+	manager := rooms
+	t.Cleanup(func() {
+		srv.Close()
+		deadline := time.Now().Add(5 * time.Second)
+		for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		// Завершившиеся горутины читали config под этими блокировками: захват упорядочивает
+		// их чтения с восстановлением config (и для детектора гонок тоже)
+		for _, room := range manager.all() {
+			room.Game.mutex.Lock()
+			room.Game.mutex.Unlock()
+		}
+	})
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+// dialTest подключается к тестовому серверу
+func dialTest(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("не удалось подключиться к %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// testMessage - сообщение сервера с нераспакованным payload
+type testMessage struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// readUntil читает текстовые сообщения, пока не придёт сообщение типа msgType.
+// Возвращает его и ошибку чтения, если соединение закрылось раньше.
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) (testMessage, error) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return testMessage{}, err
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		var msg testMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("некорректное сообщение сервера %q: %v", data, err)
+		}
+		if msg.Type == msgType {
+			return msg, nil
+		}
+	}
+}
+
+// mustReadUntil - readUntil, проваливающий тест при ошибке чтения
+func mustReadUntil(t *testing.T, conn *websocket.Conn, msgType string) testMessage {
+	t.Helper()
+	msg, err := readUntil(t, conn, msgType)
+	if err != nil {
+		t.Fatalf("не дождались сообщения %q: %v", msgType, err)
+	}
+	return msg
+}
+
+// waitFor ждёт, пока cond не станет true (проверяется под game.mutex)
+func waitFor(t *testing.T, game *GameState, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		game.mutex.RLock()
+		ok := cond()
+		game.mutex.RUnlock()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("не дождались: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGenerateIDConcurrent(t *testing.T) {
+	const goroutines, perGoroutine = 8, 1000
+
+	var counter atomic.Int64
+	ids := make(chan string, goroutines*perGoroutine)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				ids <- generateID("plr", &counter)
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool, goroutines*perGoroutine)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("ID %s выдан дважды", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != goroutines*perGoroutine {
+		t.Fatalf("выдано %d уникальных ID, ожидалось %d", len(seen), goroutines*perGoroutine)
+	}
+	if got := counter.Load(); got != goroutines*perGoroutine {
+		t.Fatalf("счётчик = %d, ожидалось %d", got, goroutines*perGoroutine)
+	}
+}
+
+// Снаряды отключившегося игрока исчезают сразу, даже пока его танк ждёт переподключения
+func TestDisconnectRemovesProjectiles(t *testing.T) {
+	withConfig(t, nil)
+	url := startTestServer(t)
+	conn := dialTest(t, url)
+	id, _ := assignedSession(t, conn)
+
+	game := rooms.defaultGame()
+	game.mutex.Lock()
+	game.Projectiles["prj-own"] = &Projectile{ID: "prj-own", OwnerID: id}
+	game.Projectiles["prj-other"] = &Projectile{ID: "prj-other", OwnerID: "plr-other"}
+	game.mutex.Unlock()
+
+	conn.Close()
+	waitFor(t, game, "отключение игрока", func() bool { return game.Players[id].MessageChan == nil })
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	if _, ok := game.Projectiles["prj-own"]; ok {
+		t.Error("снаряд отключившегося игрока остался на арене")
+	}
+	if _, ok := game.Projectiles["prj-other"]; !ok {
+		t.Error("удалён чужой снаряд")
+	}
+	if game.Players[id] == nil {
+		t.Error("танк удалён сразу, хотя должен ждать переподключения")
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// --- Карты ---
+
+// Режимы поведения на краю арены
+const (
+	EdgeClamp = "clamp" // Танки упираются в край, снаряды за краем исчезают
+	EdgeWrap  = "wrap"  // Выход за край переносит объект на противоположную сторону
+)
+
+// Порядок смены карт между раундами
+const (
+	RotationSequential = "sequential" // По порядку списка, по кругу
+	RotationRandom     = "random"     // Случайная карта, отличная от текущей (если карт больше одной)
+)
+
+// MaxProjectileWraps - сколько раз снаряд может пересечь край в режиме "wrap", прежде чем исчезнет
+const MaxProjectileWraps = 1
+
+// MapPhysics - физические параметры арены. Пропущенные в файле поля получают значения по умолчанию.
+type MapPhysics struct {
+	Gravity                   float64 `json:"gravity"`                   // Ускорение снарядов вдоль оси Y, пикселей/с²
+	Friction                  float64 `json:"friction"`                  // Доля скорости снаряда, теряемая за секунду (0..1)
+	ProjectileSpeedMultiplier float64 `json:"projectileSpeedMultiplier"` // Множитель ProjectileSpeed
+	EdgeMode                  string  `json:"edgeMode"`                  // EdgeClamp или EdgeWrap
+}
+
+// Wall - прямоугольное препятствие. Стена с Health > 0 разрушаема: снаряды отнимают здоровье,
+// и при его исчерпании стена исчезает. Стены с Health <= 0 неразрушимы.
+type Wall struct {
+	ID     string  `json:"id"`
+	X      float64 `json:"x"` // Левый верхний угол
+	Y      float64 `json:"y"`
+	W      float64 `json:"width"`
+	H      float64 `json:"height"`
+	Health int     `json:"health"`
+	Layer  uint32  `json:"layer,omitempty"` // Слой столкновений (по умолчанию LayerWall)
+
+	Destroyed bool `json:"-"` // Здоровье разрушаемой стены исчерпано, стена будет убрана в конце тика
+}
+
+// Destructible сообщает, можно ли разрушить стену
+func (w *Wall) Destructible() bool {
+	return w.Health > 0
+}
+
+// intersectsCircle проверяет пересечение стены с кругом
+func (w *Wall) intersectsCircle(cx, cy, r float64) bool {
+	nearestX := math.Max(w.X, math.Min(cx, w.X+w.W))
+	nearestY := math.Max(w.Y, math.Min(cy, w.Y+w.H))
+	dx, dy := cx-nearestX, cy-nearestY
+	return dx*dx+dy*dy < r*r
+}
+
+// MapDef описывает арену: размеры, физику и стены
+type MapDef struct {
+	Name    string     `json:"name"`
+	Width   int        `json:"width"`
+	Height  int        `json:"height"`
+	Shape   string     `json:"shape"` // ShapeRect или ShapeCircle (см. arena.go)
+	Physics MapPhysics `json:"physics"`
+	Walls   []Wall     `json:"walls"`  // Начальный набор стен (текущее состояние хранится в GameState)
+	Spawns  []Point    `json:"spawns"` // Точки появления (если пусто - случайные точки)
+
+	TeamSpawns map[int][]Point `json:"teamSpawns"` // Базы команд: точки появления по номеру команды (в командном режиме)
+	Pits       []Pit           `json:"pits"`       // Смертельные участки края арены (действуют при config.DeadlyBorders)
+}
+
+// Края арены для обрывов
+const (
+	EdgeTop    = "top"
+	EdgeBottom = "bottom"
+	EdgeLeft   = "left"
+	EdgeRight  = "right"
+)
+
+// Pit - обрыв: участок края арены, коснувшись которого танк падает и погибает.
+// From и To - координаты вдоль края (X для верхнего и нижнего, Y для левого и правого);
+// если обе нулевые, обрывом считается весь край.
+type Pit struct {
+	Edge string  `json:"edge"`
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+}
+
+// touches сообщает, касается ли танк p обрыва
+func (pit Pit) touches(p *Player, width, height float64) bool {
+	const margin = 0.5 // Танк, прижатый к краю, стоит ровно на расстоянии радиуса
+	var along float64
+	switch pit.Edge {
+	case EdgeTop:
+		along = p.X
+		if p.Y-p.Radius > margin {
+			return false
+		}
+	case EdgeBottom:
+		along = p.X
+		if p.Y+p.Radius < height-margin {
+			return false
+		}
+	case EdgeLeft:
+		along = p.Y
+		if p.X-p.Radius > margin {
+			return false
+		}
+	case EdgeRight:
+		along = p.Y
+		if p.X+p.Radius < width-margin {
+			return false
+		}
+	}
+	if pit.From == 0 && pit.To == 0 {
+		return true
+	}
+	return along >= pit.From && along <= pit.To
+}
+
+// defaultMap - пустая прямоугольная арена со стандартной физикой
+func defaultMap() *MapDef {
+	m := &MapDef{Name: "default"}
+	m.applyDefaults()
+	return m
+}
+
+// applyDefaults заполняет пропущенные поля значениями по умолчанию
+func (m *MapDef) applyDefaults() {
+	if m.Width == 0 {
+		m.Width = config.ArenaWidth
+	}
+	if m.Height == 0 {
+		m.Height = config.ArenaHeight
+	}
+	if m.Shape == "" {
+		m.Shape = ShapeRect
+	}
+	if m.Physics.ProjectileSpeedMultiplier == 0 {
+		m.Physics.ProjectileSpeedMultiplier = 1
+	}
+	if m.Physics.EdgeMode == "" {
+		m.Physics.EdgeMode = EdgeClamp
+	}
+	for i := range m.Walls {
+		if m.Walls[i].ID == "" {
+			m.Walls[i].ID = fmt.Sprintf("w%d", i+1)
+		}
+		if m.Walls[i].Layer == 0 {
+			m.Walls[i].Layer = LayerWall
+		}
+	}
+}
+
+// validate проверяет, что параметры карты имеют смысл
+func (m *MapDef) validate() error {
+	if m.Width < PlayerRadius*4 || m.Height < PlayerRadius*4 {
+		return fmt.Errorf("слишком маленькая арена %dx%d", m.Width, m.Height)
+	}
+	p := m.Physics
+	if math.IsNaN(p.Gravity) || math.IsInf(p.Gravity, 0) {
+		return fmt.Errorf("некорректная гравитация %v", p.Gravity)
+	}
+	if p.Friction < 0 || p.Friction >= 1 {
+		return fmt.Errorf("трение должно быть в диапазоне [0, 1), получено %v", p.Friction)
+	}
+	if p.ProjectileSpeedMultiplier <= 0 || p.ProjectileSpeedMultiplier > 10 {
+		return fmt.Errorf("множитель скорости снарядов должен быть в диапазоне (0, 10], получено %v", p.ProjectileSpeedMultiplier)
+	}
+	if p.EdgeMode != EdgeClamp && p.EdgeMode != EdgeWrap {
+		return fmt.Errorf("неизвестный режим края %q", p.EdgeMode)
+	}
+	if m.Shape != ShapeRect && m.Shape != ShapeCircle {
+		return fmt.Errorf("неизвестная форма арены %q", m.Shape)
+	}
+	if m.Shape == ShapeCircle && (p.EdgeMode == EdgeWrap || len(m.Pits) > 0) {
+		return fmt.Errorf("у круглой арены не бывает закольцованного края и обрывов")
+	}
+	arena := m.arena()
+	for _, s := range m.Spawns {
+		if !arena.Contains(s.X, s.Y) {
+			return fmt.Errorf("точка появления (%v, %v) за пределами арены", s.X, s.Y)
+		}
+	}
+	for team, spawns := range m.TeamSpawns {
+		if team < 1 || team > TeamCount {
+			return fmt.Errorf("база несуществующей команды %d", team)
+		}
+		for _, s := range spawns {
+			if !arena.Contains(s.X, s.Y) {
+				return fmt.Errorf("точка базы команды %d (%v, %v) за пределами арены", team, s.X, s.Y)
+			}
+		}
+	}
+	for _, pit := range m.Pits {
+		if pit.Edge != EdgeTop && pit.Edge != EdgeBottom && pit.Edge != EdgeLeft && pit.Edge != EdgeRight {
+			return fmt.Errorf("неизвестный край обрыва %q", pit.Edge)
+		}
+		if pit.From < 0 || pit.To < pit.From {
+			return fmt.Errorf("у обрыва на краю %s должно быть 0 <= from <= to, получено %v..%v", pit.Edge, pit.From, pit.To)
+		}
+	}
+	for _, w := range m.Walls {
+		if w.W <= 0 || w.H <= 0 {
+			return fmt.Errorf("стена %s имеет нулевой размер", w.ID)
+		}
+	}
+	return nil
+}
+
+// loadMap читает описание карты из JSON-файла
+func loadMap(path string) (*MapDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &MapDef{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга карты %s: %w", path, err)
+	}
+	if m.Name == "" {
+		m.Name = path
+	}
+	m.applyDefaults()
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("карта %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// setMap делает карту активной. Вызывается под game.mutex (или до запуска игровых циклов).
+func (game *GameState) setMap(m *MapDef) {
+	game.Map = m
+	game.Bounds.Width = m.Width
+	game.Bounds.Height = m.Height
+	game.Arena = m.arena()
+
+	// Стены копируются, чтобы разрушение не меняло описание карты
+	game.Walls = make([]*Wall, 0, len(m.Walls))
+	for _, w := range m.Walls {
+		wall := w
+		game.Walls = append(game.Walls, &wall)
+	}
+}
+
+// wallHitBy возвращает стену, с которой пересекается снаряд, или nil. Вызывается под game.mutex.
+func (game *GameState) wallHitBy(proj *Projectile) *Wall {
+	for _, wall := range game.Walls {
+		if !wall.Destroyed && collides(proj.Mask, wall.Layer) && wall.intersectsCircle(proj.X, proj.Y, proj.CollisionRadius) {
+			return wall
+		}
+	}
+	return nil
+}
+
+// wallBlockingTank возвращает стену, с которой пересекается танк радиуса r в точке (x, y), или nil.
+// Вызывается под game.mutex.
+func (game *GameState) wallBlockingTank(x, y, r float64) *Wall {
+	for _, wall := range game.Walls {
+		if !wall.Destroyed && wall.intersectsCircle(x, y, r) {
+			return wall
+		}
+	}
+	return nil
+}
+
+// moveTank сдвигает танк на (dx, dy), не давая заехать в стену. Оси проверяются по отдельности,
+// поэтому при движении по диагонали танк скользит вдоль стены. Вызывается под game.mutex.
+func (game *GameState) moveTank(p *Player, dx, dy float64) {
+	game.pushOutOfWalls(p)
+	if game.wallBlockingTank(p.X+dx, p.Y, p.Radius) == nil {
+		p.X += dx
+	} else {
+		p.VX = 0 // Упёрся в стену - скорость в эту сторону гаснет
+	}
+	if game.wallBlockingTank(p.X, p.Y+dy, p.Radius) == nil {
+		p.Y += dy
+	} else {
+		p.VY = 0
+	}
+}
+
+// pushOutOfWalls выталкивает танк, оказавшийся в стене (появление из сценария, смена класса
+// на более крупный), кратчайшим путём. Вызывается под game.mutex.
+func (game *GameState) pushOutOfWalls(p *Player) {
+	for i := 0; i < 4; i++ { // Выталкивание из одной стены может задвинуть в соседнюю
+		wall := game.wallBlockingTank(p.X, p.Y, p.Radius)
+		if wall == nil {
+			return
+		}
+		wall.pushOut(p)
+	}
+}
+
+// pushOut выталкивает танк из стены так, чтобы корпус лишь касался её
+func (w *Wall) pushOut(p *Player) {
+	nearestX := math.Max(w.X, math.Min(p.X, w.X+w.W))
+	nearestY := math.Max(w.Y, math.Min(p.Y, w.Y+w.H))
+	dx, dy := p.X-nearestX, p.Y-nearestY
+	if dist := math.Hypot(dx, dy); dist > 0 {
+		// Центр снаружи стены - отодвигаем вдоль линии от ближайшей точки
+		p.X = nearestX + dx/dist*p.Radius
+		p.Y = nearestY + dy/dist*p.Radius
+		return
+	}
+	// Центр внутри стены - выходим через ближайшую сторону
+	left, right := p.X-w.X, w.X+w.W-p.X
+	top, bottom := p.Y-w.Y, w.Y+w.H-p.Y
+	switch math.Min(math.Min(left, right), math.Min(top, bottom)) {
+	case left:
+		p.X = w.X - p.Radius
+	case right:
+		p.X = w.X + w.W + p.Radius
+	case top:
+		p.Y = w.Y - p.Radius
+	default:
+		p.Y = w.Y + w.H + p.Radius
+	}
+}
+
+// wrapCoord переносит координату на противоположную сторону отрезка [0, size)
+func wrapCoord(v, size float64) float64 {
+	v = math.Mod(v, size)
+	if v < 0 {
+		v += size
+	}
+	return v
+}
+
+// --- Ротация карт ---
+
+// mapRotation - карты, между которыми сервер переключается после каждого раунда (пусто - карта одна)
+var mapRotation []*MapDef
+
+// loadMapRotation загружает карты ротации из списка путей через запятую
+func loadMapRotation(list string) ([]*MapDef, error) {
+	var maps []*MapDef
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		m, err := loadMap(path)
+		if err != nil {
+			return nil, err
+		}
+		maps = append(maps, m)
+	}
+	if len(maps) == 0 {
+		return nil, fmt.Errorf("список карт ротации пуст")
+	}
+	return maps, nil
+}
+
+// pickNextMap выбирает карту следующего раунда (nil - ротации нет). Вызывается под game.mutex.
+func (game *GameState) pickNextMap() *MapDef {
+	if len(mapRotation) < 2 {
+		return nil
+	}
+	next := (game.rotationIndex + 1) % len(mapRotation)
+	if config.RotationOrder == RotationRandom {
+		next = rand.Intn(len(mapRotation) - 1)
+		if next >= game.rotationIndex {
+			next++ // Пропускаем текущую карту
+		}
+	}
+	game.rotationIndex = next
+	return mapRotation[next]
+}
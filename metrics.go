@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// --- Метрики ---
+
+// metricsRegistry - собственный реестр метрик сервера (без стандартных метрик процесса по умолчанию)
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	// unknownActionsTotal - сколько раз клиенты присылали неизвестное действие.
+	// Рост обычно означает рассинхронизацию версий клиента и сервера.
+	unknownActionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_unknown_actions_total",
+		Help: "Количество сообщений клиентов с неизвестным действием.",
+	})
+
+	// disconnectsTotal - отключения игроков по причинам (см. DisconnectReason)
+	disconnectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tanki_disconnects_total",
+		Help: "Количество отключений игроков по причинам.",
+	}, []string{"reason"})
+
+	// nonFiniteEntitiesTotal - сколько раз у объектов обнаруживались NaN/Inf в координатах
+	nonFiniteEntitiesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tanki_nonfinite_entities_total",
+		Help: "Количество исправленных объектов с NaN/Inf в координатах.",
+	}, []string{"kind"})
+
+	// invalidPayloadsTotal - сколько сообщений отброшено из-за некорректных данных (по действиям).
+	// Рост без рассинхронизации версий - повод присмотреться к клиенту.
+	invalidPayloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tanki_invalid_payloads_total",
+		Help: "Количество сообщений клиентов, отброшенных из-за некорректного payload.",
+	}, []string{"action"})
+
+	// connectionsActive - сколько WebSocket-соединений обслуживается сейчас (см. connlimit.go)
+	connectionsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tanki_connections_active",
+		Help: "Количество обслуживаемых WebSocket-соединений.",
+	})
+
+	// connectionsRejectedTotal - соединения, отклонённые из-за лимита
+	connectionsRejectedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_connections_rejected_total",
+		Help: "Количество соединений, отклонённых из-за лимита.",
+	})
+
+	// roomsActive - сколько комнат существует сейчас (см. room.go)
+	roomsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tanki_rooms_active",
+		Help: "Количество существующих игровых комнат.",
+	})
+
+	// shotsFiredTotal и hitsTotal - выстрелы и попадания по танкам во всех комнатах
+	shotsFiredTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_shots_fired_total",
+		Help: "Количество выстрелов.",
+	})
+	hitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_hits_total",
+		Help: "Количество попаданий снарядов по танкам (кроме поглощённых неуязвимостью).",
+	})
+
+	// messagesReceivedTotal, messagesDroppedTotal и messagesSentTotal - сообщения WebSocket.
+	// Сообщений в секунду - rate() от этих счётчиков.
+	messagesReceivedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_messages_received_total",
+		Help: "Количество сообщений, полученных от клиентов.",
+	})
+	messagesDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_messages_dropped_total",
+		Help: "Количество сообщений клиентов, отброшенных из-за лимита частоты.",
+	})
+	messagesSentTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tanki_messages_sent_total",
+		Help: "Количество сообщений, отправленных клиентам.",
+	})
+
+	// tickSeconds - время одного шага симуляции (updateGameLogic) под блокировкой
+	tickSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tanki_tick_seconds",
+		Help:    "Время шага симуляции.",
+		Buckets: prometheus.ExponentialBuckets(1e-5, 4, 8), // От 10 мкс до ~0.16 с
+	})
+
+	// tickPhaseSeconds - время фаз тика (заполняется только при включённом профилировании, см. profiling.go)
+	tickPhaseSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tanki_tick_phase_seconds",
+		Help:    "Время фаз игрового тика.",
+		Buckets: prometheus.ExponentialBuckets(1e-6, 4, 10), // От 1 мкс до ~0.26 с
+	}, []string{"phase"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		unknownActionsTotal,
+		disconnectsTotal,
+		nonFiniteEntitiesTotal,
+		invalidPayloadsTotal,
+		tickPhaseSeconds,
+		connectionsActive,
+		connectionsRejectedTotal,
+		roomsActive,
+		shotsFiredTotal,
+		hitsTotal,
+		messagesReceivedTotal,
+		messagesDroppedTotal,
+		messagesSentTotal,
+		tickSeconds,
+		roomCollector{},
+	)
+}
+
+// observeTick записывает время шага симуляции, начатого в start
+func observeTick(start time.Time) {
+	tickSeconds.Observe(time.Since(start).Seconds())
+}
+
+// Число игроков и снарядов читается из комнат в момент сбора метрик
+var (
+	playersDesc     = prometheus.NewDesc("tanki_players", "Количество игроков на арене (включая ботов и отключившихся, ещё не убранных).", []string{"room"}, nil)
+	projectilesDesc = prometheus.NewDesc("tanki_projectiles", "Количество снарядов в полёте.", []string{"room"}, nil)
+)
+
+// roomCollector - сборщик метрик игроков и снарядов по комнатам
+type roomCollector struct{}
+
+func (roomCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- playersDesc
+	ch <- projectilesDesc
+}
+
+func (roomCollector) Collect(ch chan<- prometheus.Metric) {
+	if rooms == nil {
+		return
+	}
+	for _, room := range rooms.all() {
+		game := room.Game
+		game.mutex.RLock()
+		players, projectiles := len(game.Players), len(game.Projectiles)
+		game.mutex.RUnlock()
+		ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(players), room.ID)
+		ch <- prometheus.MustNewConstMetric(projectilesDesc, prometheus.GaugeValue, float64(projectiles), room.ID)
+	}
+}
+
+// metricsHandler отдаёт метрики в формате Prometheus
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,55 @@
+package main
+
+import "math"
+
+// --- Разгон и торможение танка ---
+
+// Танк не трогается и не останавливается мгновенно: у него есть скорость (VX, VY), которая каждый
+// тик тянется к направлению ввода не быстрее TankAcceleration, а без ввода гасится трением
+// TankFriction. Обе величины - доли максимальной скорости класса в секунду, поэтому тяжёлый
+// и лёгкий танк разгоняются за одно и то же время. Упёршись в стену или край арены, танк
+// теряет скорость в сторону препятствия, но продолжает скользить вдоль него.
+
+const (
+	TankAcceleration = 5.0 // Разгон: долей максимальной скорости в секунду (с места до полной - за 0.2 с)
+	TankFriction     = 8.0 // Торможение без ввода: долей максимальной скорости в секунду
+)
+
+// accelerate приближает скорость танка к целевой (targetVX, targetVY) за шаг dt
+func accelerate(p *Player, targetVX, targetVY, dt float64) {
+	if targetVX == 0 && targetVY == 0 {
+		// Ввода нет - трение гасит скорость, не меняя направления
+		speed := math.Hypot(p.VX, p.VY)
+		if speed == 0 {
+			return
+		}
+		k := math.Max(0, speed-p.Speed*TankFriction*dt) / speed
+		p.VX *= k
+		p.VY *= k
+		return
+	}
+	dx, dy := targetVX-p.VX, targetVY-p.VY
+	diff := math.Hypot(dx, dy)
+	step := p.Speed * TankAcceleration * dt
+	if diff <= step {
+		p.VX, p.VY = targetVX, targetVY
+		return
+	}
+	p.VX += dx / diff * step
+	p.VY += dy / diff * step
+}
+
+// stopAgainst убирает из скорости танка составляющую, направленную из точки (x, y), где он
+// оказался бы, в точку (p.X, p.Y), куда его вернула граница. Скольжение вдоль границы остаётся.
+func stopAgainst(p *Player, x, y float64) {
+	nx, ny := p.X-x, p.Y-y // Внутрь арены
+	dist := math.Hypot(nx, ny)
+	if dist == 0 {
+		return
+	}
+	nx, ny = nx/dist, ny/dist
+	if dot := p.VX*nx + p.VY*ny; dot < 0 {
+		p.VX -= dot * nx
+		p.VY -= dot * ny
+	}
+}
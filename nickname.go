@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Проверка никнеймов ---
+
+// Никнейм показывается всем клиентам (и попадает в таблицу рекордов), поэтому сервер не верит
+// клиенту: убирает пробелы по краям, непечатаемые и управляющие символы и угловые скобки
+// (чтобы ник нельзя было вставить как HTML), а затем проверяет длину и занятость.
+
+const MaxNicknameLength = 20 // Максимальная длина никнейма в символах (рунах)
+
+// Коды ошибок в ответ на "setNickname"
+const (
+	NicknameEmpty   = "nickname_empty"
+	NicknameTooLong = "nickname_too_long"
+	NicknameTaken   = "nickname_taken"
+)
+
+// sanitizeNickname очищает никнейм. Возвращает очищенный ник и код ошибки ("" - ник подходит).
+func sanitizeNickname(raw string) (string, string) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == '<' || r == '>':
+			return -1
+		case unicode.IsSpace(r):
+			return ' ' // Табуляции, переводы строк и неразрывные пробелы - обычный пробел
+		case r == utf8.RuneError || !unicode.IsPrint(r):
+			return -1 // Управляющие, невидимые и некорректные символы
+		}
+		return r
+	}, raw)
+	nickname := strings.Join(strings.Fields(cleaned), " ") // Без пробелов по краям и повторов внутри
+	switch {
+	case nickname == "":
+		return "", NicknameEmpty
+	case utf8.RuneCountInString(nickname) > MaxNicknameLength:
+		return "", NicknameTooLong
+	}
+	return nickname, ""
+}
+
+// nicknameTaken сообщает, занят ли ник (без учёта регистра) другим игроком на арене.
+// Вызывается под game.mutex (хотя бы на чтение).
+func (game *GameState) nicknameTaken(nickname, playerID string) bool {
+	for id, p := range game.Players {
+		if id != playerID && !p.Disconnected && strings.EqualFold(p.Nickname, nickname) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package main
+
+// --- Номера ввода для предсказания на клиенте ---
+
+// Клиент может нумеровать сообщения "input" (PlayerInput.Seq, начиная с 1). Сервер применяет
+// только ввод с номером новее последнего принятого: опоздавшие и повторные сообщения
+// отбрасываются. В начале каждого тика текущий номер ввода игрока переносится в
+// Player.LastProcessedSeq, и состояние игры, в котором этот ввод уже учтён, приходит клиенту
+// вместе с номером. Клиент, предсказывающий своё движение, берёт позицию танка из состояния
+// и заново применяет поверх неё свой ввод с номерами больше LastProcessedSeq.
+//
+// Ввод без номера (Seq = 0) применяется всегда - так работают клиенты без предсказания.
+// При переподключении с токеном сессии нумерация начинается заново.
+
+// seqAfter сообщает, новее ли номер a номера b (с учётом переполнения uint32)
+func seqAfter(a, b uint32) bool {
+	return int32(a-b) > 0
+}
+
+// ackInputs отмечает текущий ввод игроков обработанным. Вызывается под game.mutex в начале тика.
+func (game *GameState) ackInputs() {
+	for _, p := range game.Players {
+		if p.Input.Seq != 0 {
+			p.LastProcessedSeq = p.Input.Seq
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// --- Профилирование ---
+
+// Пакет net/http/pprof при импорте регистрирует свои обработчики в http.DefaultServeMux,
+// поэтому основной сервер использует собственный ServeMux, а pprof доступен только на
+// отдельном админском адресе config.PprofAddr.
+
+// Фазы тика, время которых собирается в гистограмму tanki_tick_phase_seconds
+const (
+	PhaseMovement = iota
+	PhaseShooting
+	PhaseProjectiles
+	PhaseCollision
+	phaseCount
+)
+
+var phaseNames = [phaseCount]string{"movement", "shooting", "projectiles", "collision"}
+
+// phaseTimer накапливает время фаз за один тик. Без профилирования lap сводится к проверке флага.
+// Разметка приблизительная: время между двумя вызовами lap приписывается фазе второго вызова.
+type phaseTimer struct {
+	enabled bool
+	last    time.Time
+	totals  [phaseCount]time.Duration
+}
+
+// startPhaseTimer начинает замер тика (если профилирование включено)
+func startPhaseTimer() phaseTimer {
+	if config.PprofAddr == "" {
+		return phaseTimer{}
+	}
+	return phaseTimer{enabled: true, last: time.Now()}
+}
+
+// lap относит время с прошлой отметки к фазе phase
+func (t *phaseTimer) lap(phase int) {
+	if !t.enabled {
+		return
+	}
+	now := time.Now()
+	t.totals[phase] += now.Sub(t.last)
+	t.last = now
+}
+
+// observe записывает накопленное за тик время фаз в метрики
+func (t *phaseTimer) observe() {
+	if !t.enabled {
+		return
+	}
+	for phase, d := range t.totals {
+		tickPhaseSeconds.WithLabelValues(phaseNames[phase]).Observe(d.Seconds())
+	}
+}
+
+// startPprofServer запускает pprof на отдельном адресе
+func startPprofServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/timescale", handleTimeScale)
+
+	slog.Info("pprof доступен", "url", "http://"+addr+"/debug/pprof/")
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Ошибка сервера pprof", "err", err)
+		}
+	}()
+}
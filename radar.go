@@ -0,0 +1,34 @@
+package main
+
+import "math"
+
+// --- Радар ---
+
+// При -radar в состояние игры добавляется список radarBlips: положение и команда каждого живого
+// танка на арене, без остальных подробностей. Список общий для всех и не урезается радиусом
+// видимости (-view-radius), поэтому миникарта показывает всех, пока основной вид ограничен ближними.
+// Координаты округлены до пикселя - для миникарты этого хватает. В двоичном состоянии отметки
+// идут в конце сообщения (см. binarystate.go).
+
+// RadarBlip - отметка танка на радаре
+type RadarBlip struct {
+	ID   string `json:"id"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Team int    `json:"team,omitempty"`
+}
+
+// radarBlips возвращает отметки живых танков из players (nil, если радар выключен)
+func radarBlips(players []*Player) []RadarBlip {
+	if !config.Radar {
+		return nil
+	}
+	blips := make([]RadarBlip, 0, len(players))
+	for _, p := range players {
+		if p.Dead {
+			continue
+		}
+		blips = append(blips, RadarBlip{ID: p.ID, X: int(math.Round(p.X)), Y: int(math.Round(p.Y)), Team: p.Team})
+	}
+	return blips
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"time"
+)
+
+// --- Ограничение частоты сообщений ---
+
+// Каждое сообщение клиента берёт блокировку игры, поэтому клиент, присылающий тысячи сообщений
+// в секунду, тормозит всю комнату. Reader пропускает сообщения через ведро токенов: ведро
+// вмещает config.MessageBurst сообщений и пополняется со скоростью config.MessageRate в секунду.
+// Сообщения сверх лимита отбрасываются, не дойдя до блокировки. Если клиент превышает лимит
+// дольше config.FloodKick, он отключается.
+//
+// Состоянием ведра пользуется только reader своего соединения, поэтому блокировки ему не нужны.
+
+const (
+	FloodGap         = time.Second // Перерыв в отбрасывании, после которого флуд считается закончившимся
+	FloodLogInterval = time.Second // Как часто писать в журнал об отброшенных сообщениях
+)
+
+// messageLimiter - ведро токенов одного соединения
+type messageLimiter struct {
+	rate    float64 // Пополнение, токенов в секунду
+	burst   float64 // Вместимость ведра
+	tokens  float64
+	updated time.Time // Когда ведро пополнялось в последний раз
+
+	floodSince time.Time // Начало текущего превышения лимита (нулевое - превышения нет)
+	lastDrop   time.Time // Когда было отброшено последнее сообщение
+	dropped    int       // Сколько сообщений отброшено с последней записи в журнал
+	loggedAt   time.Time // Когда была последняя запись в журнал
+}
+
+// newMessageLimiter создаёт полное ведро
+func newMessageLimiter(rate float64, burst int) *messageLimiter {
+	return &messageLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// allow пополняет ведро и забирает токен под сообщение, полученное в now.
+// Возвращает false, если токенов нет и сообщение нужно отбросить.
+func (l *messageLimiter) allow(now time.Time) bool {
+	if !l.updated.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.updated).Seconds()*l.rate)
+	}
+	l.updated = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true
+	}
+
+	if l.floodSince.IsZero() || now.Sub(l.lastDrop) > FloodGap {
+		l.floodSince = now
+	}
+	l.lastDrop = now
+	l.dropped++
+	return false
+}
+
+// flooding возвращает, сколько длится текущее превышение лимита
+func (l *messageLimiter) flooding(now time.Time) time.Duration {
+	if l.floodSince.IsZero() {
+		return 0
+	}
+	return now.Sub(l.floodSince)
+}
+
+// takeDropped возвращает число отброшенных сообщений для записи в журнал, но не чаще
+// FloodLogInterval (0 - писать пока рано)
+func (l *messageLimiter) takeDropped(now time.Time) int {
+	if l.dropped == 0 || now.Sub(l.loggedAt) < FloodLogInterval {
+		return 0
+	}
+	n := l.dropped
+	l.dropped = 0
+	l.loggedAt = now
+	return n
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// --- Ретрансляция ---
+
+// В режиме ретрансляции (config.RelayUpstream) сервер не ведёт свою игру: каждое клиентское
+// соединение /ws он проксирует на вышестоящий игровой сервер. Внутренний протокол:
+//
+//   - ретранслятор открывает к вышестоящему серверу отдельное WebSocket-соединение на каждого клиента,
+//     передавая строку запроса клиента (например, ?rate=10) без изменений;
+//   - в заголовке X-Forwarded-For передаётся адрес клиента, в заголовке X-Tanki-Relay - версия протокола.
+//     Вышестоящий сервер верит этому адресу, только если соединение пришло с адреса из его
+//     config.RelayTrusted, иначе любой клиент мог бы подставить чужой IP и обойти баны и лимиты;
+//   - кадры пересылаются как есть в обе стороны, с сохранением типа (текстовый или двоичный),
+//     поэтому все сообщения клиента и сервера (input, shoot, gameState, ...) не меняются;
+//   - закрытие одной стороны закрывает другую с тем же кодом и причиной. Если вышестоящий сервер
+//     недоступен, клиент получает HTTP 502 до установки WebSocket-соединения.
+
+const (
+	RelayHeader            = "X-Tanki-Relay" // Заголовок, которым ретранслятор помечает свои соединения
+	relayCloseWriteTimeout = time.Second     // Сколько ждать отправки кадра закрытия
+)
+
+// trustedRelays - сети ретрансляторов из config.RelayTrusted (заполняется в applyConfig)
+var trustedRelays []*net.IPNet
+
+// parseRelayTrusted разбирает список адресов и подсетей через запятую. Адрес без маски -
+// подсеть из одного адреса.
+func parseRelayTrusted(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("некорректный адрес %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная подсеть %q", item)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// fromTrustedRelay сообщает, пришёл ли запрос с адреса доверенного ретранслятора
+func fromTrustedRelay(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trustedRelays {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// relayDialer - подключение к вышестоящему серверу
+var relayDialer = websocket.Dialer{HandshakeTimeout: time.Second * 5}
+
+// handleRelay проксирует клиентское WebSocket-соединение на config.RelayUpstream
+func handleRelay(w http.ResponseWriter, r *http.Request) {
+	if !acquireConnSlot() {
+		rejectConnection(w, r)
+		return
+	}
+	defer releaseConnSlot()
+
+	upstreamURL := config.RelayUpstream
+	if r.URL.RawQuery != "" {
+		upstreamURL += "?" + r.URL.RawQuery
+	}
+	header := http.Header{}
+	header.Set(RelayHeader, "1")
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		header.Set("X-Forwarded-For", host)
+	}
+
+	upstream, _, err := relayDialer.Dial(upstreamURL, header)
+	if err != nil {
+		slog.Warn("Ретрансляция: вышестоящий сервер недоступен", "remote_addr", r.RemoteAddr, "err", err)
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	client, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("Ошибка обновления до WebSocket", "err", err)
+		return
+	}
+	defer client.Close()
+	client.SetReadLimit(config.ReadLimit)
+	configureCompression(client)
+	configureCompression(upstream)
+
+	slog.Info("Ретрансляция начата", "remote_addr", client.RemoteAddr().String(), "upstream", config.RelayUpstream)
+	errc := make(chan error, 2)
+	go relayPump(upstream, client, errc)
+	go relayPump(client, upstream, errc)
+	err = <-errc // Достаточно одной закрывшейся стороны, вторая закрывается отложенными Close
+	slog.Info("Ретрансляция завершена", "remote_addr", client.RemoteAddr().String(), "err", err)
+}
+
+// relayPump пересылает кадры из src в dst, пока одна из сторон не закроется
+func relayPump(dst, src *websocket.Conn, errc chan<- error) {
+	for {
+		messageType, data, err := src.ReadMessage()
+		if err != nil {
+			code, text := websocket.CloseAbnormalClosure, ""
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				code, text = closeErr.Code, closeErr.Text
+			}
+			if code != websocket.CloseAbnormalClosure && code != websocket.CloseNoStatusReceived {
+				// Эти коды нельзя отправлять в кадре закрытия - вторая сторона просто увидит разрыв
+				dst.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(relayCloseWriteTimeout))
+			}
+			errc <- err
+			return
+		}
+		if err := dst.WriteMessage(messageType, data); err != nil {
+			errc <- err
+			return
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPTrustsOnlyConfiguredRelays(t *testing.T) {
+	withConfig(t, func(c *Config) { c.RelayTrusted = "10.0.0.5, 10.1.0.0/16" })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		relay      bool
+		want       string
+	}{
+		{"прямое соединение", "192.0.2.1:4000", false, "192.0.2.1"},
+		{"подделанный заголовок", "192.0.2.1:4000", true, "192.0.2.1"},
+		{"доверенный адрес", "10.0.0.5:4000", true, "198.51.100.7"},
+		{"доверенная подсеть", "10.1.2.3:4000", true, "198.51.100.7"},
+		{"доверенный адрес без метки ретранслятора", "10.0.0.5:4000", false, "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("X-Forwarded-For", "198.51.100.7")
+			if tt.relay {
+				r.Header.Set(RelayHeader, "1")
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Fatalf("clientIP = %s, ожидался %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRelayTrustedRejectsGarbage(t *testing.T) {
+	for _, list := range []string{"10.0.0", "10.0.0.0/33", "relay.local"} {
+		if _, err := parseRelayTrusted(list); err == nil {
+			t.Errorf("список %q принят", list)
+		}
+	}
+}
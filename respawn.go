@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// --- Гибель и возрождение ---
+
+// Танк, потерявший все жизни, выбывает из боя: не двигается, не стреляет и наблюдает за убийцей.
+// Через config.RespawnDelay он возрождается автоматически. Игрок может включить ручное возрождение
+// (настройка manualRespawn) - тогда после задержки он сам выбирает момент действием "respawn",
+// но не позже config.MaxDeadTime после гибели, чтобы не затягивать раунд.
+
+// DeathPit - причина гибели в GameEvent.Detail: танк упал в обрыв
+const DeathPit = "pit"
+
+// DeathPayload - сообщение о гибели танка (рассылается всем, чтобы клиенты показали взрыв)
+type DeathPayload struct {
+	PlayerID string  `json:"playerId"`
+	KillerID string  `json:"killerId,omitempty"` // Пусто - гибель без убийцы
+	Cause    string  `json:"cause,omitempty"`    // Пусто - попадание, DeathPit - обрыв
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+// emitDeath добавляет событие гибели ev (EventKill) вместе с сообщением "death" для всех. Вызывается под game.mutex.
+func (game *GameState) emitDeath(ev GameEvent) {
+	game.emitMessage(ev, "", ServerMessage{Type: "death", Payload: DeathPayload{
+		PlayerID: ev.TargetID,
+		KillerID: ev.PlayerID,
+		Cause:    ev.Detail,
+		X:        ev.X,
+		Y:        ev.Y,
+	}})
+}
+
+// killPlayer выводит игрока из боя. killerID - кому засчитано убийство (может быть пустым).
+// Вызывается под game.mutex.
+func (game *GameState) killPlayer(p *Player, killerID string) {
+	now := game.gameNow()
+	p.Dead = true
+	p.DiedAt = now
+	p.RespawnAt = now.Add(config.RespawnDelay)
+	p.RespawnRequested = false
+	p.SpectatingID = killerID
+	p.Input = PlayerInput{}
+	p.WantsToShoot = false
+	p.ShotQueuedAt = time.Time{}
+}
+
+// respawnDue сообщает, пора ли возродить погибшего игрока. Вызывается под game.mutex.
+func respawnDue(p *Player, now time.Time) bool {
+	if now.Before(p.RespawnAt) {
+		return false
+	}
+	if !p.ManualRespawn || p.RespawnRequested {
+		return true
+	}
+	return !now.Before(p.DiedAt.Add(config.MaxDeadTime))
+}
+
+// updateDeadPlayer обновляет погибшего игрока за тик: возрождает его, если пора,
+// и публикует обратный отсчёт. Вызывается под game.mutex.
+func (game *GameState) updateDeadPlayer(p *Player) {
+	p.Eliminated = game.eliminated(p)
+	if p.Eliminated {
+		// Выбывший не возрождается до следующего раунда, только наблюдает
+		p.RespawnMs = 0
+		p.CanFireAt = CanFireUnknown
+		if _, ok := game.Players[p.SpectatingID]; !ok {
+			p.SpectatingID = ""
+		}
+		return
+	}
+	now := game.gameNow()
+	if respawnDue(p, now) {
+		slog.Info("Игрок возрождается", "player_id", p.ID)
+		game.spawnPlayer(p)
+		return
+	}
+	p.RespawnMs = max(0, p.RespawnAt.Sub(now).Milliseconds())
+	p.CanFireAt = CanFireUnknown
+	if _, ok := game.Players[p.SpectatingID]; !ok {
+		p.SpectatingID = "" // Убийца ушёл - наблюдать не за кем
+	}
+}
+
+// dropIntoPits губит танки, коснувшиеся обрывов на краю арены (см. Pit). Вызывается под game.mutex.
+func (game *GameState) dropIntoPits() {
+	if !config.DeadlyBorders || len(game.Map.Pits) == 0 || game.Map.Physics.EdgeMode == EdgeWrap {
+		return
+	}
+	width, height := float64(game.Bounds.Width), float64(game.Bounds.Height)
+	for _, p := range game.Players {
+		if p.Dead {
+			continue
+		}
+		for _, pit := range game.Map.Pits {
+			if !pit.touches(p, width, height) {
+				continue
+			}
+			game.emitDeath(GameEvent{Kind: EventKill, TargetID: p.ID, Detail: DeathPit, X: p.X, Y: p.Y})
+			p.Lives = 0
+			p.Deaths++
+			p.Streak = 0
+			game.scoreboardDirty = true
+			game.killPlayer(p, "")
+			break
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --- Комнаты ---
+
+// Каждая комната - отдельная игра (*GameState) со своими игровым циклом, рассылкой и блокировкой,
+// поэтому комнаты не мешают друг другу. Клиент выбирает комнату при подключении: /ws?room=abc.
+// Если такой комнаты нет, она создаётся с картой по умолчанию. Когда из комнаты уходит последний
+// клиент, её циклы останавливаются, а сама комната удаляется.
+//
+// Комната по умолчанию (без параметра room) существует всё время работы сервера: в неё загружается
+// сценарий, и к ней обращаются админские ручки без параметра room.
+
+const DefaultRoomID = "main"
+
+// roomIDPattern - допустимые ID комнат (они попадают в журнал и URL)
+var roomIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// Room - одна комната с игрой
+type Room struct {
+	ID      string
+	Game    *GameState
+	clients int           // Сколько клиентов подключено (меняется под RoomManager.mutex)
+	stop    chan struct{} // Закрывается, чтобы остановить циклы комнаты
+}
+
+// RoomManager - все комнаты сервера по ID
+type RoomManager struct {
+	mutex    sync.Mutex
+	rooms    map[string]*Room
+	startMap *MapDef // Карта новых комнат
+}
+
+// rooms - комнаты сервера (создаются в main)
+var rooms *RoomManager
+
+// newGameState создаёт пустую игру на карте m
+func newGameState(m *MapDef) *GameState {
+	game := &GameState{
+		Players:       make(map[string]*Player),
+		Spectators:    make(map[string]*Spectator),
+		Projectiles:   make(map[string]*Projectile),
+		Clock:         time.Now(),
+		tickTime:      time.Now(),
+		TimeScale:     config.TimeScale,
+		botSkill:      DefaultBotSkill,
+		logSampleSeen: make(map[EventKind]int),
+	}
+	game.setMap(m)
+	game.initialPhase()
+	return game
+}
+
+// newRoomManager создаёт менеджер с запущенной комнатой по умолчанию
+func newRoomManager(startMap *MapDef) *RoomManager {
+	rm := &RoomManager{rooms: make(map[string]*Room), startMap: startMap}
+	rm.rooms[DefaultRoomID] = &Room{ID: DefaultRoomID, Game: newGameState(startMap)}
+	return rm
+}
+
+// validRoomID проверяет ID комнаты из запроса
+func validRoomID(id string) error {
+	if !roomIDPattern.MatchString(id) {
+		return fmt.Errorf("некорректный ID комнаты %q: допустимы 1-32 символа [a-zA-Z0-9_-]", id)
+	}
+	return nil
+}
+
+// defaultGame возвращает игру комнаты по умолчанию
+func (rm *RoomManager) defaultGame() *GameState {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	return rm.rooms[DefaultRoomID].Game
+}
+
+// start запускает циклы комнаты
+func (r *Room) start() {
+	r.stop = make(chan struct{})
+	go r.Game.gameLoop(r.stop)
+	go r.Game.broadcastLoop(r.stop)
+}
+
+// startDefault запускает циклы комнаты по умолчанию
+func (rm *RoomManager) startDefault() {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.rooms[DefaultRoomID].start()
+	roomsActive.Set(float64(len(rm.rooms)))
+}
+
+// join регистрирует клиента в комнате id, создавая её при необходимости.
+// Возвращает nil, если новую комнату создать нельзя из-за лимита config.MaxRooms.
+func (rm *RoomManager) join(id string) *Room {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	room, ok := rm.rooms[id]
+	if !ok {
+		if config.MaxRooms > 0 && len(rm.rooms) >= config.MaxRooms {
+			return nil
+		}
+		room = &Room{ID: id, Game: newGameState(rm.startMap)}
+		room.start()
+		rm.rooms[id] = room
+		roomsActive.Set(float64(len(rm.rooms)))
+		slog.Info("Создана комната", "room", id)
+	}
+	room.clients++
+	return room
+}
+
+// leave отмечает уход клиента из комнаты. Опустевшая комната (кроме комнаты по умолчанию)
+// останавливается и удаляется; новый клиент с тем же ID получит новую комнату.
+func (rm *RoomManager) leave(room *Room) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	room.clients--
+	if room.clients > 0 || room.ID == DefaultRoomID {
+		return
+	}
+	delete(rm.rooms, room.ID)
+	roomsActive.Set(float64(len(rm.rooms)))
+	close(room.stop)
+	slog.Info("Комната опустела и удалена", "room", room.ID)
+}
+
+// get возвращает комнату по ID или nil
+func (rm *RoomManager) get(id string) *Room {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	return rm.rooms[id]
+}
+
+// all возвращает все комнаты, отсортированные по ID
+func (rm *RoomManager) all() []*Room {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	list := make([]*Room, 0, len(rm.rooms))
+	for _, room := range rm.rooms {
+		list = append(list, room)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// roomFromRequest возвращает комнату из параметра room (по умолчанию - основную)
+// для админских ручек. Если комнаты нет, отвечает 404 и возвращает nil.
+func roomFromRequest(w http.ResponseWriter, r *http.Request) *Room {
+	id := r.URL.Query().Get("room")
+	if id == "" {
+		id = DefaultRoomID
+	}
+	room := rooms.get(id)
+	if room == nil {
+		http.Error(w, "комната не найдена", http.StatusNotFound)
+		return nil
+	}
+	return room
+}
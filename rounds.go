@@ -0,0 +1,253 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// --- Раунды ---
+
+// Игра идёт раундами. Фазы комнаты:
+//
+//	waiting      - ждём config.MinPlayers игроков (включая ботов); можно ездить и стрелять, счёт не важен
+//	playing      - идёт раунд, пока не выполнится одно из включённых условий победы
+//	intermission - перерыв: итоги раунда, ввод заморожен; затем счёт, жизни и позиции сбрасываются
+//
+// Условия победы (любые сочетания): -score-limit (первый набравший N очков), -last-standing
+// (погибший выбывает до конца раунда, побеждает последний живой танк или команда) и -round-time
+// (по истечении времени побеждает лучший по счёту; при равенстве - ничья).
+
+const (
+	DefaultScoreLimit   = 0                // Очков для победы в раунде (0 - раунд не заканчивается)
+	DefaultIntermission = time.Second * 10 // Перерыв между раундами по умолчанию (-intermission)
+)
+
+// GamePhase - фаза игры
+type GamePhase string
+
+const (
+	PhaseWaiting      GamePhase = "waiting"      // Ждём игроков для начала раунда
+	PhasePlaying      GamePhase = "playing"      // Идёт раунд
+	PhaseIntermission GamePhase = "intermission" // Перерыв: итоговая таблица, движение и стрельба заблокированы
+)
+
+// Почему закончился раунд
+const (
+	RoundEndScore        = "scoreLimit"
+	RoundEndLastStanding = "lastStanding"
+	RoundEndTime         = "timeLimit"
+)
+
+// RoundOverPayload - итоги раунда, рассылаются при переходе в перерыв
+type RoundOverPayload struct {
+	WinnerID       string `json:"winnerId"` // Пусто - ничья
+	WinnerNickname string `json:"winnerNickname"`
+	WinnerTeam     int    `json:"winnerTeam,omitempty"` // Команда-победитель (при -last-standing в командном режиме)
+	Reason         string `json:"reason"`               // RoundEndScore, RoundEndLastStanding или RoundEndTime
+	IntermissionMs int64  `json:"intermissionMs"`       // Сколько длится перерыв
+	NextMap        string `json:"nextMap"`              // Карта следующего раунда (пусто - та же)
+}
+
+// initialPhase - фаза новой комнаты. Вызывается под game.mutex (или до запуска циклов).
+func (game *GameState) initialPhase() {
+	if config.MinPlayers > 0 {
+		game.Phase = PhaseWaiting
+		game.PhaseEndsAt = time.Time{}
+		return
+	}
+	game.Phase = PhasePlaying
+	game.PhaseEndsAt = game.roundEndsAt()
+}
+
+// roundEndsAt - когда закончится раунд, начатый сейчас (нулевое время - без ограничения)
+func (game *GameState) roundEndsAt() time.Time {
+	if config.RoundTime <= 0 {
+		return time.Time{}
+	}
+	return game.gameNow().Add(config.RoundTime)
+}
+
+// participants - сколько игроков на арене (включая ботов и погибших, кроме отключившихся)
+func (game *GameState) participants() int {
+	n := 0
+	for _, p := range game.Players {
+		if !p.Disconnected {
+			n++
+		}
+	}
+	return n
+}
+
+// checkRoundStart начинает раунд, когда набралось config.MinPlayers игроков. Вызывается под game.mutex.
+func (game *GameState) checkRoundStart() {
+	if game.Phase == PhaseWaiting && game.participants() >= config.MinPlayers {
+		game.startRound()
+	}
+}
+
+// checkRoundOver завершает раунд, если выполнено одно из условий победы. Вызывается под game.mutex.
+func (game *GameState) checkRoundOver() {
+	if game.Phase != PhasePlaying {
+		return
+	}
+	if config.ScoreLimit > 0 {
+		var winner *Player
+		for _, p := range game.Players {
+			if p.Score >= config.ScoreLimit && (winner == nil || p.Score > winner.Score) {
+				winner = p
+			}
+		}
+		if winner != nil {
+			game.startIntermission(winner, 0, RoundEndScore)
+			return
+		}
+	}
+	if config.LastStanding {
+		if over, winner, team := game.lastStanding(); over {
+			game.startIntermission(winner, team, RoundEndLastStanding)
+			return
+		}
+	}
+	if !game.PhaseEndsAt.IsZero() && !game.gameNow().Before(game.PhaseEndsAt) {
+		game.startIntermission(game.topScorer(), 0, RoundEndTime)
+	}
+}
+
+// lastStanding проверяет, остался ли на арене один живой танк (в командном режиме - одна команда).
+// Возвращает победителя (nil - ничья: погибли все) и его команду. Вызывается под game.mutex.
+func (game *GameState) lastStanding() (over bool, winner *Player, team int) {
+	teams := make(map[int]bool)      // Команды всех участников
+	aliveTeams := make(map[int]bool) // Команды живых
+	for _, p := range game.Players {
+		if p.Disconnected {
+			continue
+		}
+		key := p.Team
+		if !config.TeamMode {
+			key = len(teams) + 1 // Вне командного режима каждый сам за себя
+		}
+		teams[key] = true
+		if p.Dead {
+			continue
+		}
+		aliveTeams[key] = true
+		if winner == nil || p.Score > winner.Score {
+			winner = p
+		}
+	}
+	if len(teams) < 2 || len(aliveTeams) > 1 {
+		return false, nil, 0 // Сражаться не с кем или живы несколько сторон
+	}
+	if winner != nil && config.TeamMode {
+		team = winner.Team
+	}
+	return true, winner, team
+}
+
+// topScorer - игрок с наибольшим счётом (nil при равенстве или без игроков). Вызывается под game.mutex.
+func (game *GameState) topScorer() *Player {
+	var best *Player
+	tie := false
+	for _, p := range game.Players {
+		switch {
+		case best == nil || p.Score > best.Score:
+			best, tie = p, false
+		case p.Score == best.Score:
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return best
+}
+
+// eliminated сообщает, выбыл ли погибший игрок до конца раунда (-last-standing). Вызывается под game.mutex.
+func (game *GameState) eliminated(p *Player) bool {
+	return config.LastStanding && p.Dead && game.Phase == PhasePlaying
+}
+
+// startIntermission переводит игру в перерыв между раундами. winner == nil - ничья.
+// Вызывается под game.mutex.
+func (game *GameState) startIntermission(winner *Player, winnerTeam int, reason string) {
+	game.Phase = PhaseIntermission
+	game.PhaseEndsAt = game.gameNow().Add(config.Intermission)
+	game.nextMap = game.pickNextMap()
+	nextMapName := ""
+	if game.nextMap != nil {
+		nextMapName = game.nextMap.Name
+	}
+
+	// Снаряды прошлого раунда больше не нужны, а игроки могут сменить класс до начала следующего
+	for id := range game.Projectiles {
+		delete(game.Projectiles, id)
+	}
+	for _, p := range game.Players {
+		p.Input = PlayerInput{}
+		p.WantsToShoot = false
+		p.Engaged = false
+	}
+	game.scoreboardDirty = true // Итоговая таблица уходит клиентам сразу
+
+	result := RoundOverPayload{
+		WinnerTeam:     winnerTeam,
+		Reason:         reason,
+		IntermissionMs: config.Intermission.Milliseconds(),
+		NextMap:        nextMapName,
+	}
+	if winner != nil {
+		result.WinnerID, result.WinnerNickname = winner.ID, winner.Nickname
+	}
+	slog.Info("Раунд окончен", "winner_id", result.WinnerID, "nickname", result.WinnerNickname, "reason", reason, "intermission", config.Intermission)
+	game.emitMessage(GameEvent{Kind: EventRoundOver, PlayerID: result.WinnerID, Detail: reason}, "", ServerMessage{Type: "roundOver", Payload: result})
+}
+
+// startRound сбрасывает счёт и расставляет игроков для нового раунда. Вызывается под game.mutex.
+func (game *GameState) startRound() {
+	game.Phase = PhasePlaying
+	game.PhaseEndsAt = game.roundEndsAt()
+	if game.nextMap != nil {
+		// Новая карта: размеры, стены и точки появления меняются до расстановки игроков
+		game.setMap(game.nextMap)
+		game.nextMap = nil
+		slog.Info("Карта раунда", "map", game.Map.Name, "width", game.Map.Width, "height", game.Map.Height)
+		game.emitMessage(GameEvent{Kind: EventMapChanged, Detail: game.Map.Name}, "", ServerMessage{Type: "map", Payload: game.Map})
+		game.emitMessage(GameEvent{Kind: EventWallsChanged}, "", ServerMessage{Type: "walls", Payload: game.Walls})
+	}
+	game.NoFireUntil = game.gameNow().Add(config.NoFireDuration)
+	for _, p := range game.Players {
+		if p.Disconnected {
+			continue
+		}
+		p.Score = 0
+		p.Kills = 0
+		p.Deaths = 0
+		p.Streak = 0
+		game.spawnPlayer(p)
+	}
+	game.scoreboardDirty = true
+	slog.Info("Начинается новый раунд")
+	game.emitMessage(GameEvent{Kind: EventRoundStart}, "", ServerMessage{Type: "roundStart", Payload: nil})
+}
+
+// noFireRemainingMs - сколько миллисекунд игроку ещё нельзя стрелять: общая разминка раунда
+// или личный запрет после появления, смотря что дольше
+func (game *GameState) noFireRemainingMs(p *Player) int64 {
+	return max(0, game.noFireUntil(p).Sub(game.gameNow()).Milliseconds())
+}
+
+// noFireUntil - до какого игрового момента игроку нельзя стрелять
+func (game *GameState) noFireUntil(p *Player) time.Time {
+	if p.NoFireUntil.After(game.NoFireUntil) {
+		return p.NoFireUntil
+	}
+	return game.NoFireUntil
+}
+
+// phaseRemainingMs - сколько миллисекунд осталось до конца текущей фазы (0, если фаза бессрочная)
+func (game *GameState) phaseRemainingMs() int64 {
+	if game.PhaseEndsAt.IsZero() {
+		return 0
+	}
+	return max(0, game.PhaseEndsAt.Sub(game.gameNow()).Milliseconds())
+}
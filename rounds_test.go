@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Перерыв между раундами берётся из -intermission; ноль - следующий раунд начинается на ближайшем тике
+func TestIntermissionConfigurable(t *testing.T) {
+	withConfig(t, func(c *Config) { c.Intermission = 3 * time.Second })
+	game := newGameState(defaultMap())
+	game.Phase = PhasePlaying
+	game.startIntermission(nil, 0, RoundEndTime)
+	if got := game.PhaseEndsAt.Sub(game.gameNow()); got != 3*time.Second {
+		t.Fatalf("перерыв %v, ожидалось 3s", got)
+	}
+
+	withConfig(t, func(c *Config) { c.Intermission = 0 })
+	game.Phase = PhasePlaying
+	game.startIntermission(nil, 0, RoundEndTime)
+	game.updateGameLogic(0.05, time.Now())
+	if game.Phase != PhasePlaying {
+		t.Fatalf("фаза %q после тика с нулевым перерывом, ожидалась %q", game.Phase, PhasePlaying)
+	}
+
+	c := defaultConfig()
+	c.Intermission = -time.Second
+	if err := c.validate(); err == nil || !strings.Contains(err.Error(), "intermission") {
+		t.Fatalf("отрицательный -intermission принят: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestSanitizeEntities(t *testing.T) {
+	withConfig(t, nil)
+	game := newGameState(defaultMap())
+	lost := &Player{ID: "plr-lost", X: math.NaN(), Y: 100, Lives: 3, Radius: PlayerRadius}
+	spun := &Player{ID: "plr-spun", X: 300, Y: 300, AimAngle: math.Inf(1), Lives: 3, Radius: PlayerRadius,
+		Input: PlayerInput{AimX: 5, AimY: 5, AimByAngle: true}}
+	fine := &Player{ID: "plr-fine", X: 500, Y: 400, AimAngle: 1, Lives: 3, Radius: PlayerRadius}
+	for _, p := range []*Player{lost, spun, fine} {
+		game.Players[p.ID] = p
+	}
+	game.Projectiles["prj-bad"] = &Projectile{ID: "prj-bad", X: 10, Y: 10, VX: math.NaN()}
+	game.Projectiles["prj-ok"] = &Projectile{ID: "prj-ok", X: 10, Y: 10, VX: 1}
+
+	game.sanitizeEntities()
+
+	if !isFinite(lost.X) || !isFinite(lost.Y) {
+		t.Errorf("игрок с NaN-позицией не перемещён: (%v, %v)", lost.X, lost.Y)
+	}
+	if spun.AimAngle != 0 || spun.DesiredAimAngle != 0 || spun.Input.AimX != 0 || spun.Input.AimByAngle {
+		t.Errorf("некорректный угол не сброшен: угол %v, ввод %+v", spun.AimAngle, spun.Input)
+	}
+	if fine.X != 500 || fine.Y != 400 || fine.AimAngle != 1 {
+		t.Errorf("корректный игрок изменён: (%v, %v), угол %v", fine.X, fine.Y, fine.AimAngle)
+	}
+	if _, ok := game.Projectiles["prj-bad"]; ok {
+		t.Error("снаряд с NaN-скоростью не удалён")
+	}
+	if _, ok := game.Projectiles["prj-ok"]; !ok {
+		t.Error("корректный снаряд удалён")
+	}
+
+	// Главное следствие: состояние снова кодируется в JSON
+	if _, err := json.Marshal(game.Players); err != nil {
+		t.Fatalf("состояние после очистки не кодируется в JSON: %v", err)
+	}
+}
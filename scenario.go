@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// --- Сценарии ---
+
+// Сценарий задаёт начальное состояние арены: заранее расставленных игроков и летящие снаряды.
+// Игроки сценария не имеют соединения (сообщения им не отправляются) и стоят на месте,
+// пока ими не управляет сервер (Bot). Удобно для воспроизводимых проверок и демонстраций.
+
+// ScenarioPlayer - игрок сценария. Пропущенные поля получают значения по умолчанию.
+type ScenarioPlayer struct {
+	ID        string  `json:"id"` // Пусто - сгенерировать
+	Nickname  string  `json:"nickname"`
+	Class     string  `json:"class"` // Пусто - DefaultTankClass
+	Color     string  `json:"color"` // Пусто - случайный цвет
+	Team      int     `json:"team"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	AimAngle  float64 `json:"aimAngle"`
+	BodyAngle float64 `json:"bodyAngle"`
+	Score     int     `json:"score"`
+	Lives     int     `json:"lives"` // 0 - стартовые жизни класса
+	Bot       bool    `json:"bot"`
+}
+
+// ScenarioProjectile - снаряд сценария
+type ScenarioProjectile struct {
+	OwnerID string  `json:"ownerId"` // ID игрока сценария
+	Weapon  string  `json:"weapon"`  // Оружие снаряда (пусто - оружие владельца)
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	VX      float64 `json:"vx"`
+	VY      float64 `json:"vy"`
+}
+
+// Scenario - начальное состояние арены
+type Scenario struct {
+	Players     []ScenarioPlayer     `json:"players"`
+	Projectiles []ScenarioProjectile `json:"projectiles"`
+}
+
+// loadScenario читает сценарий из JSON-файла и проверяет его для карты m
+func loadScenario(path string, m *MapDef) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scenario{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга сценария %s: %w", path, err)
+	}
+	if err := s.validate(m); err != nil {
+		return nil, fmt.Errorf("сценарий %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// validate проверяет сценарий относительно карты m
+func (s *Scenario) validate(m *MapDef) error {
+	ids := make(map[string]bool, len(s.Players))
+	for i, p := range s.Players {
+		if p.Class != "" {
+			if _, ok := tankClasses[p.Class]; !ok {
+				return fmt.Errorf("игрок %d: неизвестный класс %q", i+1, p.Class)
+			}
+		}
+		if p.Color != "" && !validColor(p.Color) {
+			return fmt.Errorf("игрок %d: некорректный цвет %q", i+1, p.Color)
+		}
+		if p.X < 0 || p.Y < 0 || p.X > float64(m.Width) || p.Y > float64(m.Height) {
+			return fmt.Errorf("игрок %d за пределами арены (%v, %v)", i+1, p.X, p.Y)
+		}
+		if p.Team < 0 || p.Team > TeamCount {
+			return fmt.Errorf("игрок %d: некорректная команда %d", i+1, p.Team)
+		}
+		if p.ID != "" {
+			if ids[p.ID] {
+				return fmt.Errorf("повторяющийся ID игрока %q", p.ID)
+			}
+			ids[p.ID] = true
+		}
+	}
+	for i, proj := range s.Projectiles {
+		if !ids[proj.OwnerID] {
+			return fmt.Errorf("снаряд %d: владелец %q не найден среди игроков сценария с ID", i+1, proj.OwnerID)
+		}
+		if _, ok := weapons[proj.Weapon]; proj.Weapon != "" && !ok {
+			return fmt.Errorf("снаряд %d: неизвестное оружие %q", i+1, proj.Weapon)
+		}
+	}
+	return nil
+}
+
+// applyScenario добавляет игроков и снаряды сценария на арену. Вызывается под game.mutex
+// (или до запуска игровых циклов).
+func (game *GameState) applyScenario(s *Scenario) {
+	for _, sp := range s.Players {
+		id := sp.ID
+		if id == "" {
+			id = generateID("plr", &nextPlayerID)
+		}
+		class := sp.Class
+		if class == "" {
+			class = DefaultTankClass
+		}
+		p := &Player{
+			ID:        id,
+			Nickname:  sp.Nickname,
+			Color:     sp.Color,
+			Team:      sp.Team,
+			Score:     sp.Score,
+			Bot:       sp.Bot,
+			Layer:     LayerPlayer,
+			X:         sp.X,
+			Y:         sp.Y,
+			AimAngle:  sp.AimAngle,
+			BodyAngle: sp.BodyAngle,
+
+			DesiredAimAngle: sp.AimAngle,
+		}
+		applyTankClass(p, tankClasses[class])
+		applyWeapon(p, weapons[DefaultWeapon])
+		if sp.Lives > 0 {
+			p.Lives = sp.Lives
+		}
+		if p.Nickname == "" {
+			p.Nickname = "Player " + id
+		}
+		if p.Color == "" {
+			p.Color = randomColor()
+		}
+		game.Players[id] = p
+	}
+
+	for _, sp := range s.Projectiles {
+		id := generateID("p", &nextProjectileID)
+		weapon := sp.Weapon
+		if weapon == "" {
+			weapon = game.Players[sp.OwnerID].Weapon
+		}
+		collisionRadius, visualRadius := weapons[weapon].projectileRadii()
+		game.Projectiles[id] = &Projectile{
+			ID:      id,
+			OwnerID: sp.OwnerID,
+			Weapon:  weapon,
+			Layer:   LayerProjectile,
+			Mask:    DefaultProjectileMask,
+			Damage:  ProjectileDamage,
+			X:       sp.X,
+			Y:       sp.Y,
+			VX:      sp.VX,
+			VY:      sp.VY,
+			OriginX: sp.X,
+			OriginY: sp.Y,
+
+			SpawnTime: game.gameNow(),
+
+			ExplosionRadius: weapons[weapon].ExplosionRadius,
+			CollisionRadius: collisionRadius,
+			VisualRadius:    visualRadius,
+		}
+	}
+	game.scoreboardDirty = true
+	slog.Info("Загружен сценарий", "players", len(s.Players), "projectiles", len(s.Projectiles))
+}
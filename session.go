@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// --- Переподключение ---
+
+// При подключении игрок получает в сообщении "assignId" токен сессии. Если соединение оборвалось,
+// танк остаётся на арене ещё config.ReconnectGrace, и клиент, подключившийся с этим токеном
+// (/ws?token=...), продолжает игру тем же игроком: счёт, жизни и позиция сохраняются. Токен
+// действует только в своей комнате, поэтому переподключаться нужно с тем же параметром room.
+//
+// Если у игрока с этим токеном ещё открыто соединение (клиент пропал без закрытия TCP и сразу
+// подключился снова, открыта вторая вкладка или токен украден), решает config.SessionConflict:
+//
+//	SessionTakeover - новое соединение перехватывает игрока, а старое закрывается с кодом 1008
+//	                  (policy violation) и причиной SessionTakenOverReason
+//	SessionReject   - новое соединение получает ошибку session_in_use и закрывается с кодом 1008,
+//	                  старое продолжает игру
+//
+// Обмен соединений идёт под game.mutex: writer старого соединения завершается по закрытию его
+// канала, а reader видит, что player.Conn уже другой, и закрывает только своё соединение.
+
+const SessionTokenBytes = 16 // Длина токена сессии до кодирования в hex
+
+// Что делать с подключением по токену, у игрока которого уже есть живое соединение
+const (
+	SessionTakeover = "takeover" // Новое соединение перехватывает игрока
+	SessionReject   = "reject"   // Новое соединение отклоняется
+)
+
+// SessionTakenOverReason - причина в закрывающем кадре соединения, игрока которого перехватило новое
+const SessionTakenOverReason = "session resumed elsewhere"
+
+// newSessionToken создаёт случайный токен сессии
+func newSessionToken() string {
+	b := make([]byte, SessionTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("Ошибка генерации токена сессии", "err", err)
+		return "" // Без токена игрок просто не сможет переподключиться
+	}
+	return hex.EncodeToString(b)
+}
+
+// sessionPlayer возвращает игрока с токеном token или nil. Вызывается под game.mutex.
+func (game *GameState) sessionPlayer(token string) *Player {
+	if token == "" {
+		return nil
+	}
+	for _, p := range game.Players {
+		if p.SessionToken != "" && subtle.ConstantTimeCompare([]byte(p.SessionToken), []byte(token)) == 1 {
+			return p
+		}
+	}
+	return nil
+}
+
+// resumeSession привязывает игрока к новому соединению conn. Вызывается под game.mutex.
+func resumeSession(p *Player, conn *websocket.Conn) {
+	if p.MessageChan != nil {
+		// Старое соединение ещё открыто: сообщаем клиенту причину, его writer завершится
+		// по закрытию канала, а reader увидит, что игрок перехвачен (см. reader)
+		msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, SessionTakenOverReason)
+		p.Conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(connRejectWriteTimeout))
+		close(p.MessageChan)
+		p.Conn.Close()
+	}
+	p.Conn = conn
+	p.MessageChan = make(chan []byte, 32)
+	p.Disconnected = false
+	p.Input = PlayerInput{}
+	p.InputSeq, p.LastProcessedSeq = 0, 0 // Новое соединение нумерует ввод заново
+	p.WantsToShoot = false
+	p.LingerUntil = time.Time{}
+	p.DisconnectReason = ""
+	p.delta = nil // Новому соединению нужно полное состояние
+	p.NextStateSend = time.Time{}
+}
+
+// rejectSessionInUse закрывает новое соединение conn, токен которого уже занят живым соединением
+func rejectSessionInUse(conn *websocket.Conn) {
+	connectionsRejectedTotal.Inc()
+	deadline := time.Now().Add(connRejectWriteTimeout)
+	conn.SetWriteDeadline(deadline)
+	conn.WriteJSON(ServerMessage{Type: "error", Payload: ErrorPayload{Code: "session_in_use", Message: "session is in use by another connection"}})
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session in use")
+	conn.WriteControl(websocket.CloseMessage, msg, deadline)
+	conn.Close()
+}
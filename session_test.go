@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// assignedSession ждёт assignId на conn и возвращает ID и токен из assignId
+func assignedSession(t *testing.T, conn *websocket.Conn) (id, token string) {
+	t.Helper()
+	msg := mustReadUntil(t, conn, "assignId")
+	var payload map[string]string
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		t.Fatalf("некорректный assignId: %v", err)
+	}
+	return payload["id"], payload["token"]
+}
+
+func TestSessionTakeover(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SessionConflict = SessionTakeover })
+	url := startTestServer(t)
+
+	oldConn := dialTest(t, url)
+	id, token := assignedSession(t, oldConn)
+
+	newConn := dialTest(t, url+"?token="+token)
+	newID, _ := assignedSession(t, newConn)
+	if newID != id {
+		t.Fatalf("после перехвата ID = %s, ожидался прежний %s", newID, id)
+	}
+
+	// Старое соединение получает close frame с причиной, а не просто обрыв
+	_, err := readUntil(t, oldConn, "never")
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("старое соединение закрыто без close frame: %v", err)
+	}
+	if closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != SessionTakenOverReason {
+		t.Fatalf("close frame = %d %q, ожидался %d %q", closeErr.Code, closeErr.Text, websocket.ClosePolicyViolation, SessionTakenOverReason)
+	}
+
+	// Reader старого соединения, завершаясь, не должен отключить игрока с нового соединения
+	time.Sleep(100 * time.Millisecond)
+	game := rooms.defaultGame()
+	game.mutex.RLock()
+	player := game.Players[id]
+	connected := player != nil && player.MessageChan != nil
+	game.mutex.RUnlock()
+	if !connected {
+		t.Fatalf("игрок %s отключён после перехвата сессии", id)
+	}
+}
+
+func TestSessionReject(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SessionConflict = SessionReject })
+	url := startTestServer(t)
+
+	oldConn := dialTest(t, url)
+	id, token := assignedSession(t, oldConn)
+
+	newConn := dialTest(t, url+"?token="+token)
+	msg := mustReadUntil(t, newConn, "error")
+	var payload ErrorPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		t.Fatalf("некорректная ошибка: %v", err)
+	}
+	if payload.Code != "session_in_use" {
+		t.Fatalf("код ошибки = %q, ожидался session_in_use", payload.Code)
+	}
+	_, err := readUntil(t, newConn, "never")
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+		t.Fatalf("новое соединение закрыто не с кодом %d: %v", websocket.ClosePolicyViolation, err)
+	}
+
+	// Старое соединение по-прежнему принадлежит игроку
+	game := rooms.defaultGame()
+	game.mutex.RLock()
+	player := game.Players[id]
+	sameConn := player != nil && player.MessageChan != nil
+	game.mutex.RUnlock()
+	if !sameConn {
+		t.Fatalf("игрок %s потерял соединение после отклонённого подключения", id)
+	}
+}
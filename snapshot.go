@@ -0,0 +1,104 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// --- Снимок состояния в PNG ---
+
+var (
+	snapshotBackground = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	snapshotOutside    = color.RGBA{0x11, 0x11, 0x11, 0xff} // За краем круглой арены
+	snapshotWall       = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	snapshotWallWeak   = color.RGBA{0x8a, 0x6d, 0x4b, 0xff}
+	snapshotProjectile = color.RGBA{0xff, 0xff, 0x00, 0xff}
+)
+
+// snapshotCircle - круг для отрисовки (игрок или снаряд)
+type snapshotCircle struct {
+	x, y, r float64
+	c       color.RGBA
+}
+
+// handleSnapshot рисует текущее состояние арены: игроки - цветные круги,
+// снаряды - точки, стены - прямоугольники. Масштаб 1 пиксель = 1 единица игры.
+// Комната выбирается параметром room (по умолчанию - основная).
+func handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	room := roomFromRequest(w, r)
+	if room == nil {
+		return
+	}
+	game := room.Game
+	// Под блокировкой только копируем данные, рисуем уже без неё
+	game.mutex.RLock()
+	width, height := game.Bounds.Width, game.Bounds.Height
+	arena := game.Arena
+	walls := make([]Wall, 0, len(game.Walls))
+	for _, wall := range game.Walls {
+		walls = append(walls, *wall)
+	}
+	circles := make([]snapshotCircle, 0, len(game.Players)+len(game.Projectiles))
+	for _, p := range game.Players {
+		circles = append(circles, snapshotCircle{p.X, p.Y, p.Radius, parseHexColor(p.Color)})
+	}
+	for _, p := range game.Projectiles {
+		circles = append(circles, snapshotCircle{p.X, p.Y, p.VisualRadius, snapshotProjectile})
+	}
+	game.mutex.RUnlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if circle, ok := arena.(CircleArena); ok {
+		draw.Draw(img, img.Bounds(), &image.Uniform{snapshotOutside}, image.Point{}, draw.Src)
+		fillCircle(img, snapshotCircle{circle.CX, circle.CY, circle.R, snapshotBackground})
+	} else {
+		draw.Draw(img, img.Bounds(), &image.Uniform{snapshotBackground}, image.Point{}, draw.Src)
+	}
+	for _, wall := range walls {
+		c := snapshotWall
+		if wall.Destructible() {
+			c = snapshotWallWeak
+		}
+		rect := image.Rect(int(wall.X), int(wall.Y), int(wall.X+wall.W), int(wall.Y+wall.H))
+		draw.Draw(img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	for _, c := range circles {
+		fillCircle(img, c)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := png.Encode(w, img); err != nil {
+		slog.Error("Ошибка кодирования снимка", "err", err)
+	}
+}
+
+// fillCircle закрашивает круг на изображении
+func fillCircle(img *image.RGBA, c snapshotCircle) {
+	bounds := img.Bounds()
+	for y := int(c.y - c.r); y <= int(c.y+c.r); y++ {
+		for x := int(c.x - c.r); x <= int(c.x+c.r); x++ {
+			dx, dy := float64(x)-c.x, float64(y)-c.y
+			if dx*dx+dy*dy <= c.r*c.r && image.Pt(x, y).In(bounds) {
+				img.SetRGBA(x, y, c.c)
+			}
+		}
+	}
+}
+
+// parseHexColor разбирает цвет вида "#rrggbb"; при ошибке возвращает белый
+func parseHexColor(s string) color.RGBA {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{0xff, 0xff, 0xff, 0xff}
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
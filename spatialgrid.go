@@ -0,0 +1,55 @@
+package main
+
+import "math"
+
+// --- Пространственная сетка ---
+
+// Чтобы не проверять каждый снаряд с каждым игроком, перед движением снарядов игроки раскладываются
+// по квадратным ячейкам со стороной GridCellSize (по ячейке центра танка). Снаряд проверяется только
+// с игроками из ячеек, до которых может дотянуться столкновение: радиус снаряда плюс радиус самого
+// большого танка. Сама проверка столкновения не меняется - сетка лишь сужает список кандидатов.
+
+const GridCellSize = 2 * PlayerRadius // Сторона ячейки сетки
+
+// gridCell - координаты ячейки сетки
+type gridCell struct{ x, y int }
+
+// playerGrid - игроки по ячейкам. Перестраивается каждый тик.
+type playerGrid struct {
+	cells     map[gridCell][]*Player
+	maxRadius float64   // Радиус самого большого танка в сетке
+	found     []*Player // Буфер результата candidates
+}
+
+// cellOf возвращает ячейку точки
+func cellOf(x, y float64) gridCell {
+	return gridCell{int(math.Floor(x / GridCellSize)), int(math.Floor(y / GridCellSize))}
+}
+
+// rebuild раскладывает игроков по ячейкам
+func (g *playerGrid) rebuild(players map[string]*Player) {
+	if g.cells == nil {
+		g.cells = make(map[gridCell][]*Player)
+	}
+	clear(g.cells)
+	g.maxRadius = 0
+	for _, p := range players {
+		c := cellOf(p.X, p.Y)
+		g.cells[c] = append(g.cells[c], p)
+		g.maxRadius = max(g.maxRadius, p.Radius)
+	}
+}
+
+// candidates возвращает игроков, с которыми может столкнуться круг радиуса r в точке (x, y).
+// Результат действителен до следующего вызова.
+func (g *playerGrid) candidates(x, y, r float64) []*Player {
+	g.found = g.found[:0]
+	reach := r + g.maxRadius
+	from, to := cellOf(x-reach, y-reach), cellOf(x+reach, y+reach)
+	for cx := from.x; cx <= to.x; cx++ {
+		for cy := from.y; cy <= to.y; cy++ {
+			g.found = append(g.found, g.cells[gridCell{cx, cy}]...)
+		}
+	}
+	return g.found
+}
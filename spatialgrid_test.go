@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// scatterEntities раскладывает игроков и снаряды случайно (но воспроизводимо) по арене 2000x1500
+func scatterEntities(players, projectiles int) (map[string]*Player, []*Projectile) {
+	rng := rand.New(rand.NewSource(1))
+	ps := make(map[string]*Player, players)
+	for i := 0; i < players; i++ {
+		id := fmt.Sprintf("plr-%d", i)
+		ps[id] = &Player{ID: id, X: rng.Float64() * 2000, Y: rng.Float64() * 1500, Radius: PlayerRadius}
+	}
+	projs := make([]*Projectile, projectiles)
+	for i := range projs {
+		projs[i] = &Projectile{ID: fmt.Sprintf("prj-%d", i), X: rng.Float64() * 2000, Y: rng.Float64() * 1500, CollisionRadius: 5}
+	}
+	return ps, projs
+}
+
+// overlaps - та же проверка столкновения снаряда с танком, что в updateGameLogic
+func overlaps(proj *Projectile, p *Player) bool {
+	dx, dy := proj.X-p.X, proj.Y-p.Y
+	r := p.Radius + proj.CollisionRadius
+	return dx*dx+dy*dy < r*r
+}
+
+// hitsAllPairs - прежняя проверка каждого снаряда с каждым игроком
+func hitsAllPairs(players map[string]*Player, projs []*Projectile) []string {
+	var hits []string
+	for _, proj := range projs {
+		for _, p := range players {
+			if overlaps(proj, p) {
+				hits = append(hits, proj.ID+"/"+p.ID)
+			}
+		}
+	}
+	return hits
+}
+
+// hitsGrid - проверка только с кандидатами из сетки
+func hitsGrid(g *playerGrid, players map[string]*Player, projs []*Projectile) []string {
+	var hits []string
+	g.rebuild(players)
+	for _, proj := range projs {
+		for _, p := range g.candidates(proj.X, proj.Y, proj.CollisionRadius) {
+			if overlaps(proj, p) {
+				hits = append(hits, proj.ID+"/"+p.ID)
+			}
+		}
+	}
+	return hits
+}
+
+func TestGridFindsSameHitsAsAllPairs(t *testing.T) {
+	players, projs := scatterEntities(50, 2000)
+	// Танки на границах ячеек и снаряды вплотную к ним - самые опасные для сетки случаи
+	players["edge"] = &Player{ID: "edge", X: GridCellSize * 3, Y: GridCellSize * 2, Radius: PlayerRadius * 2}
+	projs = append(projs, &Projectile{ID: "near-edge", X: GridCellSize*3 - PlayerRadius*2 - 4, Y: GridCellSize * 2, CollisionRadius: 5})
+
+	want := hitsAllPairs(players, projs)
+	got := hitsGrid(&playerGrid{}, players, projs)
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("сетка нашла %d попаданий, полный перебор - %d", len(got), len(want))
+	}
+	if !slices.Contains(got, "near-edge/edge") {
+		t.Fatal("попадание в большой танк из соседней ячейки не найдено")
+	}
+}
+
+// go test -bench ProjectileCollisions -run '^$'
+func BenchmarkProjectileCollisions(b *testing.B) {
+	players, projs := scatterEntities(50, 500)
+	b.Run("allPairs", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hitsAllPairs(players, projs)
+		}
+	})
+	b.Run("grid", func(b *testing.B) {
+		var g playerGrid
+		for i := 0; i < b.N; i++ {
+			hitsGrid(&g, players, projs)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// --- Точки появления ---
+
+const SpawnCandidates = 16 // Сколько случайных точек перебирать, если на карте нет точек появления
+
+// Направление танка при появлении
+const (
+	SpawnFaceCenter = "center"       // К центру карты
+	SpawnFaceEnemy  = "nearestEnemy" // К ближайшему противнику (или к центру, если противников нет)
+)
+
+// Что снимает защиту после появления раньше срока config.SpawnInvulnerability
+const (
+	ProtectionBreakFire  = "fire"  // Выстрел
+	ProtectionBreakMove  = "move"  // Любое действие: движение или выстрел
+	ProtectionBreakTimer = "timer" // Ничего - защита действует до конца срока
+)
+
+// breakSpawnProtection снимает защиту после появления, если действие action (ProtectionBreakFire
+// или ProtectionBreakMove) её снимает по config.SpawnProtectionBreak. Вызывается под game.mutex.
+func breakSpawnProtection(p *Player, action string) {
+	if !p.Invulnerable {
+		return
+	}
+	policy := config.SpawnProtectionBreak
+	if policy == ProtectionBreakTimer || (policy == ProtectionBreakFire && action != ProtectionBreakFire) {
+		return
+	}
+	p.InvulUntil = time.Time{}
+	p.Invulnerable = false
+	slog.Debug("Игрок потерял защиту после появления", "player_id", p.ID, "action", action)
+}
+
+// Point - точка на арене
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// chooseSpawn выбирает место появления для игрока p.
+// Кандидаты - точки базы команды игрока, иначе точки появления карты или случайные точки.
+// Отбрасываются кандидаты внутри стен и ближе config.MinSpawnDistance к живому противнику. Из оставшихся
+// берётся первый, а при config.SpawnWeightExponent > 0 - случайный с весом, растущим с расстоянием
+// до ближайшего противника. Если подходящих нет, ограничение по дистанции ослабляется
+// и берётся кандидат, максимально удалённый от противников. Вызывается под game.mutex.
+func (game *GameState) chooseSpawn(p *Player) (float64, float64) {
+	radius := p.Radius
+	candidates := game.spawnCandidates(p.Team, radius)
+
+	var eligible []Point
+	var weights []float64
+	var best Point
+	bestDist := -1.0
+	for _, c := range candidates {
+		if game.spawnBlockedByWall(c, radius) {
+			continue
+		}
+		dist := game.nearestEnemyDistance(p, c)
+		if dist >= config.MinSpawnDistance {
+			if config.SpawnWeightExponent == 0 {
+				return c.X, c.Y
+			}
+			eligible = append(eligible, c)
+			weights = append(weights, spawnWeight(dist))
+			continue
+		}
+		if dist > bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	if len(eligible) > 0 {
+		pt := eligible[weightedIndex(weights)]
+		return pt.X, pt.Y
+	}
+	if bestDist < 0 {
+		// Все кандидаты внутри стен - просто берём случайную точку
+		return game.randomPosition(radius)
+	}
+	return best.X, best.Y
+}
+
+// spawnWeight - вес кандидата, до ближайшего противника от которого dist. Чем больше показатель,
+// тем сильнее выбор смещается к пустым участкам арены. Без противников все кандидаты равноценны.
+func spawnWeight(dist float64) float64 {
+	if math.IsInf(dist, 1) {
+		return 1
+	}
+	return math.Pow(dist, config.SpawnWeightExponent)
+}
+
+// weightedIndex выбирает случайный индекс с вероятностью, пропорциональной весу
+func weightedIndex(weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(weights) - 1 // Погрешность округления
+}
+
+// spawnPlayer возвращает игрока в бой: применяет выбранный класс, восстанавливает жизни
+// и выбирает место появления. Вызывается под game.mutex.
+func (game *GameState) spawnPlayer(p *Player) {
+	className := p.Class
+	if p.PendingClass != "" {
+		className = p.PendingClass
+	}
+	applyTankClass(p, tankClasses[className])
+	p.X, p.Y = game.chooseSpawn(p)
+	setAim(p, game.spawnFacingAngle(p))
+	p.BodyAngle = p.AimAngle
+	p.Input = PlayerInput{}
+	p.VX, p.VY = 0, 0
+	p.WantsToShoot = false
+	p.ShotQueuedAt = time.Time{}
+	p.Engaged = false
+	p.Dead = false
+	p.Eliminated = false
+	p.RespawnMs = 0
+	p.SpectatingID = ""
+	p.RespawnRequested = false
+	game.emit(GameEvent{Kind: EventSpawn, PlayerID: p.ID})
+	if config.NoFireOnRespawn {
+		p.NoFireUntil = game.gameNow().Add(config.NoFireDuration)
+	}
+	p.InvulUntil = game.gameNow().Add(config.SpawnInvulnerability)
+}
+
+// spawnFacingAngle - угол, под которым танк смотрит после появления в точке (p.X, p.Y)
+func (game *GameState) spawnFacingAngle(p *Player) float64 {
+	targetX, targetY := float64(game.Bounds.Width)/2, float64(game.Bounds.Height)/2
+	if config.SpawnFacing == SpawnFaceEnemy {
+		nearest := math.Inf(1)
+		for id, other := range game.Players {
+			if id == p.ID || other.Lives <= 0 {
+				continue
+			}
+			if d := math.Hypot(other.X-p.X, other.Y-p.Y); d < nearest {
+				nearest, targetX, targetY = d, other.X, other.Y
+			}
+		}
+	}
+	if math.Hypot(targetX-p.X, targetY-p.Y) < 1 {
+		return 0 // Появились ровно в цели - смотрим вправо
+	}
+	return math.Atan2(targetY-p.Y, targetX-p.X)
+}
+
+// spawnCandidates возвращает перемешанные точки базы команды team, точки появления карты
+// или набор случайных точек - что есть на карте, в таком порядке
+func (game *GameState) spawnCandidates(team int, radius float64) []Point {
+	points := game.Map.Spawns
+	if teamSpawns := game.Map.TeamSpawns[team]; config.TeamMode && len(teamSpawns) > 0 {
+		points = teamSpawns
+	}
+	if len(points) > 0 {
+		candidates := append([]Point(nil), points...)
+		rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		return candidates
+	}
+	candidates := make([]Point, SpawnCandidates)
+	for i := range candidates {
+		candidates[i].X, candidates[i].Y = game.randomPosition(radius)
+	}
+	return candidates
+}
+
+// spawnBlockedByWall сообщает, пересекается ли танк в точке p со стеной
+func (game *GameState) spawnBlockedByWall(p Point, radius float64) bool {
+	for _, wall := range game.Walls {
+		if !wall.Destroyed && wall.intersectsCircle(p.X, p.Y, radius) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestEnemyDistance - расстояние от точки pt до ближайшего живого противника игрока p (+Inf, если их нет).
+// Союзники в командном режиме противниками не считаются.
+func (game *GameState) nearestEnemyDistance(p *Player, pt Point) float64 {
+	nearest := math.Inf(1)
+	for id, other := range game.Players {
+		if id == p.ID || other.Lives <= 0 || (p.Team != 0 && other.Team == p.Team) {
+			continue
+		}
+		nearest = math.Min(nearest, math.Hypot(other.X-pt.X, other.Y-pt.Y))
+	}
+	return nearest
+}
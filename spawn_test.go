@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// spawnTestGame - игра на пустой арене с точками появления spawns и противником в enemy
+func spawnTestGame(spawns []Point, enemy Point) (*GameState, *Player) {
+	m := defaultMap()
+	m.Spawns = spawns
+	game := newGameState(m)
+	game.Players["plr-enemy"] = &Player{ID: "plr-enemy", X: enemy.X, Y: enemy.Y, Lives: 3, Radius: PlayerRadius}
+	p := &Player{ID: "plr-new", Lives: 3, Radius: PlayerRadius}
+	game.Players[p.ID] = p
+	return game, p
+}
+
+func TestChooseSpawnAvoidsEnemies(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MinSpawnDistance = 150 })
+	near, far := Point{100, 100}, Point{700, 500}
+	game, p := spawnTestGame([]Point{near, far}, Point{120, 100})
+	for i := 0; i < 50; i++ { // Кандидаты перемешиваются - проверяем много раз
+		if x, y := game.chooseSpawn(p); x != far.X || y != far.Y {
+			t.Fatalf("танк появился в (%v, %v) рядом с противником, ожидалось (%v, %v)", x, y, far.X, far.Y)
+		}
+	}
+}
+
+func TestChooseSpawnFallsBackToFarthest(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MinSpawnDistance = 150 })
+	// Обе точки ближе -min-spawn-distance к противнику - берётся более далёкая
+	game, p := spawnTestGame([]Point{{100, 100}, {200, 100}}, Point{110, 100})
+	for i := 0; i < 50; i++ {
+		if x, _ := game.chooseSpawn(p); x != 200 {
+			t.Fatalf("выбрана точка x=%v, ожидалась самая далёкая от противника x=200", x)
+		}
+	}
+}
+
+// На маленькой карте расстояние можно уменьшить, и ближняя точка становится допустимой
+func TestMinSpawnDistanceConfigurable(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MinSpawnDistance = 50 })
+	game, p := spawnTestGame([]Point{{100, 100}}, Point{170, 100})
+	if x, y := game.chooseSpawn(p); x != 100 || y != 100 {
+		t.Fatalf("танк появился в (%v, %v), ожидалась точка (100, 100) в 70 px от противника", x, y)
+	}
+	c := defaultConfig()
+	c.MinSpawnDistance = -1
+	if err := c.validate(); err == nil || !strings.Contains(err.Error(), "min-spawn-distance") {
+		t.Fatalf("отрицательное -min-spawn-distance принято: %v", err)
+	}
+}
+
+func TestChooseSpawnIgnoresDeadEnemies(t *testing.T) {
+	withConfig(t, nil)
+	game, p := spawnTestGame(nil, Point{100, 100})
+	game.Players["plr-enemy"].Lives = 0
+	if d := game.nearestEnemyDistance(p, Point{100, 100}); !math.IsInf(d, 1) {
+		t.Fatalf("погибший противник учтён: расстояние %v", d)
+	}
+}
+
+func TestTeamSpawnsAtBase(t *testing.T) {
+	withConfig(t, func(c *Config) { c.TeamMode = true })
+	m := defaultMap()
+	m.Spawns = []Point{{400, 300}}
+	m.TeamSpawns = map[int][]Point{1: {{100, 100}}, 2: {{900, 700}}}
+	game := newGameState(m)
+	for team, base := range map[int]Point{1: {100, 100}, 2: {900, 700}} {
+		p := &Player{ID: "plr-new", Team: team, Radius: PlayerRadius}
+		if x, y := game.chooseSpawn(p); x != base.X || y != base.Y {
+			t.Errorf("команда %d появилась в (%v, %v), ожидалась база (%v, %v)", team, x, y, base.X, base.Y)
+		}
+	}
+}
+
+func TestSpawnInvulnerability(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SpawnInvulnerability = time.Second })
+	game := newGameState(defaultMap())
+	p := &Player{ID: "plr-new", Class: DefaultTankClass}
+	game.Players[p.ID] = p
+	game.spawnPlayer(p)
+	lives := p.Lives
+
+	shot := &Projectile{ID: "prj-1", OwnerID: "plr-enemy", Damage: 1}
+	game.applyProjectileHit(shot, p)
+	if p.Lives != lives {
+		t.Fatalf("танк под защитой после появления потерял жизнь: %d из %d", p.Lives, lives)
+	}
+
+	game.Clock = game.Clock.Add(time.Second) // Защита истекла
+	game.applyProjectileHit(shot, p)
+	if p.Lives != lives-1 {
+		t.Fatalf("после конца защиты жизней %d, ожидалось %d", p.Lives, lives-1)
+	}
+}
+
+func TestBreakSpawnProtection(t *testing.T) {
+	tests := []struct {
+		policy, action string
+		broken         bool
+	}{
+		{ProtectionBreakFire, ProtectionBreakFire, true},
+		{ProtectionBreakFire, ProtectionBreakMove, false},
+		{ProtectionBreakMove, ProtectionBreakFire, true},
+		{ProtectionBreakMove, ProtectionBreakMove, true},
+		{ProtectionBreakTimer, ProtectionBreakFire, false},
+		{ProtectionBreakTimer, ProtectionBreakMove, false},
+	}
+	for _, tt := range tests {
+		withConfig(t, func(c *Config) { c.SpawnProtectionBreak = tt.policy })
+		until := time.Now().Add(time.Second)
+		p := &Player{ID: "plr-1", Invulnerable: true, InvulUntil: until}
+		breakSpawnProtection(p, tt.action)
+		if broken := !p.Invulnerable && p.InvulUntil.IsZero(); broken != tt.broken {
+			t.Errorf("политика %s, действие %s: защита снята = %v, ожидалось %v", tt.policy, tt.action, broken, tt.broken)
+		}
+		if !tt.broken && !p.InvulUntil.Equal(until) {
+			t.Errorf("политика %s, действие %s: срок защиты изменён", tt.policy, tt.action)
+		}
+	}
+}
+
+// Выстрел в игровом цикле снимает защиту при политике fire
+func TestShotBreaksSpawnProtection(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SpawnProtectionBreak = ProtectionBreakFire })
+	game, shooter := firingTestGame(t)
+	shooter.Invulnerable, shooter.InvulUntil = true, game.gameNow().Add(time.Minute)
+	shooter.WantsToShoot = true
+	game.updateGameLogic(0, time.Now())
+	if shooter.Invulnerable || !shooter.InvulUntil.IsZero() {
+		t.Fatal("выстрел не снял защиту после появления")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// --- Зрители ---
+
+// Клиент, подключившийся к /ws?spectate=1, становится зрителем: он получает общие рассылки
+// (полное состояние игры в JSON без отсечения по радиусу видимости, таблицу очков, события раунда),
+// но танка у него нет, в game.Players он не попадает и на игру никак не влияет. Зрители хранятся
+// отдельно в game.Spectators и не занимают мест в комнате (-max-players).
+//
+// Вместо assignId зритель получает сообщение "spectating" со своим ID, затем карту и стены.
+// Из действий зрителю доступно только "join": он становится обычным игроком на том же соединении
+// (получит assignId, как при обычном подключении). Если комната заполнена, придёт ошибка
+// server_full, а зритель останется зрителем. Прочие действия зрителя игнорируются.
+
+// Spectator - подключённый зритель
+type Spectator struct {
+	ID          string
+	Conn        *websocket.Conn
+	MessageChan chan []byte // nil, когда зритель ушёл или стал игроком
+	IP          string
+	ConnectedAt time.Time
+	BinaryState bool // Формат состояния после "join" (сам зритель получает JSON)
+
+	writerDone chan struct{} // Закрывается, когда writer зрителя больше не пишет в соединение
+}
+
+// addSpectator регистрирует зрителя с соединением conn и запускает его reader и writer. Вызывается под game.mutex.
+func (game *GameState) addSpectator(room *Room, conn *websocket.Conn, ip string, binaryState bool) {
+	s := &Spectator{
+		ID:          generateID("spc", &nextSpectatorID),
+		Conn:        conn,
+		MessageChan: make(chan []byte, 32),
+		IP:          ip,
+		ConnectedAt: time.Now(),
+		BinaryState: binaryState,
+		writerDone:  make(chan struct{}),
+	}
+	game.Spectators[s.ID] = s
+	slog.Info("Подключился зритель", "spectator_id", s.ID, "remote_addr", conn.RemoteAddr().String(), "room", room.ID)
+
+	spectatingBytes, _ := json.Marshal(ServerMessage{Type: "spectating", Payload: map[string]string{"id": s.ID}})
+	mapBytes, _ := json.Marshal(ServerMessage{Type: "map", Payload: game.Map})
+	wallsBytes, _ := json.Marshal(ServerMessage{Type: "walls", Payload: game.Walls})
+	for _, b := range [][]byte{spectatingBytes, mapBytes, wallsBytes} {
+		queueSpectator(s, b)
+	}
+
+	go game.spectatorWriter(s, s.MessageChan)
+	go game.spectatorReader(room, s)
+}
+
+// queueSpectator - неблокирующая отправка сообщения зрителю
+func queueSpectator(s *Spectator, msgBytes []byte) {
+	if s.MessageChan == nil {
+		return
+	}
+	select {
+	case s.MessageChan <- msgBytes:
+	default:
+		slog.Warn("Канал сообщений зрителя переполнен", "spectator_id", s.ID)
+	}
+}
+
+// queueSpectators отправляет сообщение всем зрителям. Вызывается под game.mutex (хотя бы на чтение).
+func (game *GameState) queueSpectators(msgBytes []byte) {
+	for _, s := range game.Spectators {
+		queueSpectator(s, msgBytes)
+	}
+}
+
+// spectatorWriter пишет сообщения из канала зрителя в соединение, пока канал не закроют
+func (game *GameState) spectatorWriter(s *Spectator, messageChan chan []byte) {
+	defer close(s.writerDone)
+
+	ping := time.NewTicker(config.PingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case message, ok := <-messageChan:
+			if !ok {
+				return // Зритель ушёл или стал игроком
+			}
+			err = s.Conn.WriteMessage(websocket.TextMessage, message)
+			if err == nil {
+				messagesSentTotal.Inc()
+				messageBytesSentTotal.Add(float64(len(message)))
+			}
+		case <-ping.C:
+			err = s.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(PingWriteTimeout))
+		}
+		if err != nil {
+			slog.Warn("Ошибка записи сообщения зрителю", "spectator_id", s.ID, "err", err)
+			s.Conn.Close() // Разбудит reader, который выполнит очистку
+			return
+		}
+	}
+}
+
+// spectatorReader читает сообщения зрителя до отключения или перехода в игроки
+func (game *GameState) spectatorReader(room *Room, s *Spectator) {
+	if game.readSpectator(room, s) {
+		return // Соединение перешло к игроку вместе с местом в комнате
+	}
+	game.mutex.Lock()
+	delete(game.Spectators, s.ID)
+	close(s.MessageChan)
+	s.MessageChan = nil
+	game.mutex.Unlock()
+	s.Conn.Close()
+	releaseConnSlot()
+	rooms.leave(room)
+	slog.Info("Зритель отключился", "spectator_id", s.ID)
+}
+
+// readSpectator обрабатывает сообщения зрителя. Возвращает true, если зритель стал игроком.
+func (game *GameState) readSpectator(room *Room, s *Spectator) bool {
+	conn := s.Conn
+	conn.SetReadLimit(config.ReadLimit)
+	conn.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	})
+	limiter := newMessageLimiter(config.MessageRate, config.MessageBurst)
+
+	for {
+		messageType, message, err := conn.ReadMessage()
+		receivedAt := time.Now()
+		if err != nil {
+			slog.Debug("Соединение зрителя закрыто", "spectator_id", s.ID, "err", err)
+			return false
+		}
+		conn.SetReadDeadline(receivedAt.Add(config.PongTimeout))
+
+		messagesReceivedTotal.Inc()
+		if !limiter.allow(receivedAt) {
+			messagesDroppedTotal.Inc()
+			continue
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		var msg ClientMessage
+		if err := json.Unmarshal(message, &msg); err != nil || msg.Action != "join" {
+			continue
+		}
+		if game.joinSpectator(room, s) {
+			return true
+		}
+	}
+}
+
+// joinSpectator превращает зрителя в игрока на том же соединении. Возвращает false, если
+// комната заполнена (зритель остаётся зрителем). Вызывается из reader зрителя без блокировок.
+func (game *GameState) joinSpectator(room *Room, s *Spectator) bool {
+	game.mutex.Lock()
+	if game.roomFull() {
+		data, _ := json.Marshal(ServerMessage{Type: "error", Payload: ErrorPayload{Code: "server_full", Message: "server full", Action: "join"}})
+		queueSpectator(s, data)
+		game.mutex.Unlock()
+		return false
+	}
+	delete(game.Spectators, s.ID)
+	close(s.MessageChan) // Writer зрителя допишет текущее сообщение и завершится
+	s.MessageChan = nil
+	game.pendingJoins++ // Место занято: пока ждём writer, его не должен забрать другой клиент
+	game.mutex.Unlock()
+
+	<-s.writerDone // У соединения может быть только один писатель
+
+	game.mutex.Lock()
+	game.pendingJoins--
+	player := game.addPlayer(s.Conn, s.IP, s.BinaryState)
+	slog.Info("Зритель вступил в игру", "spectator_id", s.ID, "player_id", player.ID)
+	game.startConnection(room, player)
+	game.mutex.Unlock()
+	return true
+}
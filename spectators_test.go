@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRoomFullCountsPendingJoins(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxPlayers = 1 })
+	game := newGameState(defaultMap())
+	if game.roomFull() {
+		t.Fatal("пустая комната считается заполненной")
+	}
+	game.pendingJoins = 1
+	if !game.roomFull() {
+		t.Fatal("место вступающего зрителя не учтено в лимите комнаты")
+	}
+}
+
+// Два зрителя одновременно вступают в игру, а место одно: игроком должен стать ровно один
+func TestSpectatorJoinRace(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxPlayers = 1 })
+	url := startTestServer(t)
+
+	conns := []*websocket.Conn{dialTest(t, url+"?spectate=1"), dialTest(t, url+"?spectate=1")}
+	for _, conn := range conns {
+		mustReadUntil(t, conn, "spectating")
+	}
+
+	results := make([]string, len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := conn.WriteJSON(ClientMessage{Action: "join"}); err != nil {
+				results[i] = err.Error()
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				var msg testMessage
+				if err := conn.ReadJSON(&msg); err != nil {
+					results[i] = err.Error()
+					return
+				}
+				if msg.Type == "assignId" || msg.Type == "error" {
+					results[i] = msg.Type
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	players := 0
+	for _, r := range results {
+		switch r {
+		case "assignId":
+			players++
+		case "error":
+		default:
+			t.Fatalf("зритель не получил ответа на join: %s", r)
+		}
+	}
+	if players != 1 {
+		t.Fatalf("игроками стали %d зрителей при одном месте: %v", players, results)
+	}
+	game := rooms.defaultGame()
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	if len(game.Players) != 1 || game.pendingJoins != 0 {
+		t.Fatalf("в комнате %d игроков и %d незавершённых вступлений, ожидались 1 и 0", len(game.Players), game.pendingJoins)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+)
+
+// --- Разбиение состояния игры на части ---
+
+// Если сериализованное сообщение gameState больше config.MaxStateMessageSize, вместо него
+// отправляется несколько сообщений "gameStateChunk". Каждая часть - обычный GameStatePayload
+// со своей долей игроков и снарядов и общими полями (фаза, обратные отсчёты), плюс конверт
+// (лента событий и радар - только в первой части):
+//
+//	snapshot - номер снимка: все части одного снимка имеют одинаковый номер
+//	index    - номер части, от 0 до total-1
+//	total    - сколько всего частей в снимке
+//
+// Клиент собирает части снимка, объединяя списки players и projectiles, и применяет снимок,
+// когда получены все total частей. Части приходят по порядку; если пришла часть более нового
+// снимка, недособранный старый снимок отбрасывается.
+
+// GameStateChunkPayload - часть снимка состояния игры
+type GameStateChunkPayload struct {
+	Snapshot uint64 `json:"snapshot"`
+	Index    int    `json:"index"`
+	Total    int    `json:"total"`
+	GameStatePayload
+}
+
+// gameStateMessages сериализует состояние игры в одно сообщение gameState или, если оно
+// больше config.MaxStateMessageSize, в несколько сообщений gameStateChunk
+func (game *GameState) gameStateMessages(payload GameStatePayload) ([][]byte, error) {
+	msgBytes, err := json.Marshal(ServerMessage{Type: "gameState", Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return game.chunkGameState(payload, msgBytes)
+}
+
+// chunkGameState разбивает уже сериализованное сообщение gameState msgBytes, если оно слишком большое
+func (game *GameState) chunkGameState(payload GameStatePayload, msgBytes []byte) ([][]byte, error) {
+	limit := config.MaxStateMessageSize
+	if limit <= 0 || len(msgBytes) <= limit {
+		return [][]byte{msgBytes}, nil
+	}
+
+	game.stateSnapshot++
+	entities := len(payload.Players) + len(payload.Projectiles)
+	// Сущности примерно одного размера, поэтому начинаем с числа частей по общему размеру
+	// и увеличиваем его, пока каждая часть не уложится в предел
+	for total := (len(msgBytes) + limit - 1) / limit; ; total++ {
+		chunks, fits, err := game.splitGameState(payload, total)
+		if err != nil {
+			return nil, err
+		}
+		if fits || total >= entities {
+			if !fits {
+				slog.Warn("Часть состояния игры превышает лимит даже по одной сущности", "limit", limit)
+			}
+			return chunks, nil
+		}
+	}
+}
+
+// splitGameState делит игроков и снаряды поровну на total частей и сообщает,
+// уложилась ли каждая часть в config.MaxStateMessageSize
+func (game *GameState) splitGameState(payload GameStatePayload, total int) ([][]byte, bool, error) {
+	chunks := make([][]byte, 0, total)
+	fits := true
+	for i := 0; i < total; i++ {
+		part := payload
+		part.Players = payload.Players[len(payload.Players)*i/total : len(payload.Players)*(i+1)/total]
+		part.Projectiles = payload.Projectiles[len(payload.Projectiles)*i/total : len(payload.Projectiles)*(i+1)/total]
+		if i > 0 {
+			part.Events = nil
+			part.RadarBlips = nil
+		}
+		chunk, err := json.Marshal(ServerMessage{Type: "gameStateChunk", Payload: GameStateChunkPayload{
+			Snapshot:         game.stateSnapshot,
+			Index:            i,
+			Total:            total,
+			GameStatePayload: part,
+		}})
+		if err != nil {
+			return nil, false, err
+		}
+		if len(chunk) > config.MaxStateMessageSize {
+			fits = false
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, fits, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// --- Статистика игроков ---
+
+// PlayerStats - публичная статистика одного игрока
+type PlayerStats struct {
+	ID           string  `json:"id"`
+	Nickname     string  `json:"nickname"`
+	Color        string  `json:"color"`
+	Class        string  `json:"class"`
+	Team         int     `json:"team"`
+	Score        int     `json:"score"`
+	Lives        int     `json:"lives"`
+	Kills        int     `json:"kills"`
+	Deaths       int     `json:"deaths"`
+	ShotsFired   int     `json:"shotsFired"`
+	ShotsHit     int     `json:"shotsHit"`
+	Accuracy     float64 `json:"accuracy"`     // Доля попаданий от 0 до 1
+	BorderMisses int     `json:"borderMisses"` // Снарядов улетело за край арены (считается при -border-misses)
+	Streak       int     `json:"streak"`       // Убийств подряд без смерти
+}
+
+// statsOf собирает статистику игрока. Вызывается под game.mutex (хотя бы на чтение).
+func statsOf(p *Player) PlayerStats {
+	accuracy := 0.0
+	if p.ShotsFired > 0 {
+		accuracy = float64(p.ShotsHit) / float64(p.ShotsFired)
+	}
+	return PlayerStats{
+		ID:           p.ID,
+		Nickname:     p.Nickname,
+		Color:        p.Color,
+		Class:        p.Class,
+		Team:         p.Team,
+		Score:        p.Score,
+		Lives:        p.Lives,
+		Kills:        p.Kills,
+		Deaths:       p.Deaths,
+		ShotsFired:   p.ShotsFired,
+		ShotsHit:     p.ShotsHit,
+		Accuracy:     accuracy,
+		BorderMisses: p.BorderMisses,
+		Streak:       p.Streak,
+	}
+}
+
+// handlePlayerStats - GET /player/{id}: статистика одного игрока или 404.
+// ID игроков уникальны на весь сервер, поэтому игрок ищется во всех комнатах.
+func handlePlayerStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var stats PlayerStats
+	ok := false
+	for _, room := range rooms.all() {
+		game := room.Game
+		game.mutex.RLock()
+		if p, found := game.Players[id]; found {
+			stats, ok = statsOf(p), true
+		}
+		game.mutex.RUnlock()
+		if ok {
+			break
+		}
+	}
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
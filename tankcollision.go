@@ -0,0 +1,61 @@
+package main
+
+import "math"
+
+// --- Столкновения танков ---
+
+// Если включено config.TankCollision, перекрывающиеся танки после движения расталкиваются
+// поровну вдоль линии между центрами. Подбитые танки (см. solid) не мешают никому,
+// а союзники в командном режиме при config.TeammatePassThrough проезжают друг сквозь друга.
+
+// tanksCollide сообщает, должны ли танки a и b расталкиваться
+func tanksCollide(a, b *Player) bool {
+	if !solid(a) || !solid(b) {
+		return false
+	}
+	return !(teammates(a, b) && config.TeammatePassThrough)
+}
+
+// resolveTankCollisions расталкивает перекрывающиеся танки. Вызывается под game.mutex.
+func (game *GameState) resolveTankCollisions() {
+	if !config.TankCollision {
+		return
+	}
+	players := make([]*Player, 0, len(game.Players))
+	for _, p := range game.Players {
+		players = append(players, p)
+	}
+	for i, a := range players {
+		for _, b := range players[i+1:] {
+			if !tanksCollide(a, b) {
+				continue
+			}
+			dx, dy := b.X-a.X, b.Y-a.Y
+			dist := math.Hypot(dx, dy)
+			overlap := a.Radius + b.Radius - dist
+			if overlap <= 0 {
+				continue
+			}
+			if dist == 0 {
+				dx, dy, dist = 1, 0, 1 // Танки точно друг на друге - расталкиваем по горизонтали
+			}
+			push := overlap / 2 / dist
+			a.X, a.Y = a.X-dx*push, a.Y-dy*push
+			b.X, b.Y = b.X+dx*push, b.Y+dy*push
+			game.keepInArena(a)
+			game.keepInArena(b)
+		}
+	}
+}
+
+// keepInArena возвращает танк в пределы арены после движения или расталкивания
+func (game *GameState) keepInArena(p *Player) {
+	if game.Map.Physics.EdgeMode == EdgeWrap {
+		p.X = wrapCoord(p.X, float64(game.Bounds.Width))
+		p.Y = wrapCoord(p.Y, float64(game.Bounds.Height))
+		return
+	}
+	x, y := p.X, p.Y
+	p.X, p.Y = game.Arena.Clamp(x, y, p.Radius)
+	stopAgainst(p, x, y) // Скорость в сторону края гаснет
+}
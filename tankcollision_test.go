@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// overlappingPair ставит два танка на 20 пикселей друг от друга (радиусы 15 + 15)
+func overlappingPair(game *GameState, teamA, teamB int) (*Player, *Player) {
+	a := &Player{ID: "plr-a", X: 400, Y: 300, Radius: PlayerRadius, Team: teamA, Lives: 3}
+	b := &Player{ID: "plr-b", X: 420, Y: 300, Radius: PlayerRadius, Team: teamB, Lives: 3}
+	game.Players[a.ID], game.Players[b.ID] = a, b
+	return a, b
+}
+
+func TestTankCollisionPushesApart(t *testing.T) {
+	withConfig(t, func(c *Config) { c.TankCollision = true })
+	game := newGameState(defaultMap())
+	a, b := overlappingPair(game, 0, 0)
+	game.resolveTankCollisions()
+	if d := math.Hypot(b.X-a.X, b.Y-a.Y); math.Abs(d-2*PlayerRadius) > 1e-9 {
+		t.Fatalf("после расталкивания между центрами %v, ожидалось %v", d, 2*PlayerRadius)
+	}
+	if a.X != 395 || b.X != 425 {
+		t.Fatalf("танки сдвинуты неравно: %v и %v, ожидалось 395 и 425", a.X, b.X)
+	}
+}
+
+func TestTeammatesPassThrough(t *testing.T) {
+	withConfig(t, func(c *Config) { c.TankCollision, c.TeamMode, c.TeammatePassThrough = true, true, true })
+	game := newGameState(defaultMap())
+	a, b := overlappingPair(game, 1, 1)
+	game.resolveTankCollisions()
+	if a.X != 400 || b.X != 420 {
+		t.Fatalf("союзники расталкиваются: %v и %v", a.X, b.X)
+	}
+
+	// Противники по-прежнему сталкиваются
+	game = newGameState(defaultMap())
+	a, b = overlappingPair(game, 1, 2)
+	game.resolveTankCollisions()
+	if a.X == 400 || b.X == 420 {
+		t.Fatal("противники проезжают друг сквозь друга")
+	}
+}
+
+func TestDeadTanksDoNotCollide(t *testing.T) {
+	withConfig(t, func(c *Config) { c.TankCollision = true })
+	game := newGameState(defaultMap())
+	a, b := overlappingPair(game, 0, 0)
+	b.Dead = true
+	game.resolveTankCollisions()
+	if a.X != 400 || b.X != 420 {
+		t.Fatalf("подбитый танк расталкивает живой: %v и %v", a.X, b.X)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// --- Команды ---
+
+const (
+	TeamCount         = 2                // Количество команд в командном режиме
+	RebalanceInterval = time.Second * 10 // Как часто проверять перекос команд (не чаще)
+)
+
+// TeamSwitchedPayload - игрок переведён в другую команду при автобалансе
+type TeamSwitchedPayload struct {
+	PlayerID string `json:"playerId"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+}
+
+// teamSizes возвращает число участников и суммарный счёт каждой команды (индексы 1..TeamCount).
+// Отключившиеся танки не учитываются. Вызывается под game.mutex.
+func (game *GameState) teamSizes() (sizes, scores [TeamCount + 1]int) {
+	for _, p := range game.Players {
+		if p.Team == 0 || p.Disconnected {
+			continue
+		}
+		sizes[p.Team]++
+		scores[p.Team] += p.Score
+	}
+	return sizes, scores
+}
+
+// teammates сообщает, в одной ли команде игроки a и b (вне командного режима - никогда)
+func teammates(a, b *Player) bool {
+	return config.TeamMode && a.Team != 0 && a.Team == b.Team
+}
+
+// friendlyProjectile сообщает, выпущен ли снаряд союзником игрока. Без config.FriendlyFire
+// такие снаряды пролетают сквозь союзников. Вызывается под game.mutex.
+func (game *GameState) friendlyProjectile(proj *Projectile, player *Player) bool {
+	owner, ok := game.Players[proj.OwnerID]
+	return ok && teammates(owner, player)
+}
+
+// teamScores - суммарный счёт команд для рассылки (nil вне командного режима). Вызывается под game.mutex.
+func (game *GameState) teamScores() map[int]int {
+	if !config.TeamMode {
+		return nil
+	}
+	_, scores := game.teamSizes()
+	result := make(map[int]int, TeamCount)
+	for t := 1; t <= TeamCount; t++ {
+		result[t] = scores[t]
+	}
+	return result
+}
+
+// assignTeam выбирает команду для нового игрока: меньшую по числу участников,
+// при равенстве - с меньшим суммарным счётом. Вне командного режима возвращает 0.
+// Вызывается под game.mutex.
+func (game *GameState) assignTeam() int {
+	if !config.TeamMode {
+		return 0
+	}
+	sizes, scores := game.teamSizes()
+	best := 1
+	for t := 2; t <= TeamCount; t++ {
+		if sizes[t] < sizes[best] || (sizes[t] == sizes[best] && scores[t] < scores[best]) {
+			best = t
+		}
+	}
+	return best
+}
+
+// rebalanceTeams переводит одного игрока из самой большой команды в самую маленькую, если разница
+// превышает config.RebalanceThreshold. Срабатывает не чаще RebalanceInterval и за раз переводит только
+// одного игрока, предпочитая тех, кто сейчас не в бою. Вызывается под game.mutex.
+func (game *GameState) rebalanceTeams() {
+	if !config.TeamMode || !config.AutoRebalance || time.Since(game.lastRebalance) < RebalanceInterval {
+		return
+	}
+	game.lastRebalance = time.Now()
+
+	sizes, _ := game.teamSizes()
+	largest, smallest := 1, 1
+	for t := 2; t <= TeamCount; t++ {
+		if sizes[t] > sizes[largest] {
+			largest = t
+		}
+		if sizes[t] < sizes[smallest] {
+			smallest = t
+		}
+	}
+	if sizes[largest]-sizes[smallest] <= config.RebalanceThreshold {
+		return
+	}
+
+	// Кандидат: сначала не участвующие в бою, среди них - с наименьшим счётом
+	var candidate *Player
+	for _, p := range game.Players {
+		if p.Team != largest || p.Disconnected {
+			continue
+		}
+		if candidate == nil ||
+			(candidate.Engaged && !p.Engaged) ||
+			(candidate.Engaged == p.Engaged && p.Score < candidate.Score) {
+			candidate = p
+		}
+	}
+	if candidate == nil {
+		return
+	}
+
+	candidate.Team = smallest
+	game.scoreboardDirty = true
+	slog.Info("Автобаланс: игрок переведён в другую команду", "player_id", candidate.ID, "from", largest, "to", smallest)
+	game.emitMessage(GameEvent{Kind: EventTeamSwitched, PlayerID: candidate.ID}, "",
+		ServerMessage{Type: "teamSwitched", Payload: TeamSwitchedPayload{PlayerID: candidate.ID, From: largest, To: smallest}})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Отрицательный или нечисловой шаг (например, после перевода часов) ничего не двигает назад
+func TestNonPositiveDtDoesNotMove(t *testing.T) {
+	withConfig(t, nil)
+	for _, dt := range []float64{-0.5, math.NaN(), math.Inf(-1)} {
+		game, p := firingTestGame(t)
+		p.Input.Right = true
+		game.Projectiles["prj-1"] = &Projectile{ID: "prj-1", OwnerID: p.ID, X: 500, Y: 500, VX: 100, SpawnTime: game.gameNow()}
+		clock := game.Clock
+
+		game.updateGameLogic(dt, time.Now())
+
+		if p.X != 200 || p.Y != 200 {
+			t.Errorf("dt=%v: танк сдвинулся в (%v, %v)", dt, p.X, p.Y)
+		}
+		if proj := game.Projectiles["prj-1"]; proj == nil || proj.X != 500 {
+			t.Errorf("dt=%v: снаряд сдвинулся или исчез: %+v", dt, proj)
+		}
+		if game.Clock.Before(clock) {
+			t.Errorf("dt=%v: игровые часы пошли назад", dt)
+		}
+	}
+}
+
+func TestPositiveDtMoves(t *testing.T) {
+	withConfig(t, nil)
+	game, p := firingTestGame(t)
+	game.Projectiles["prj-1"] = &Projectile{ID: "prj-1", OwnerID: p.ID, X: 500, Y: 500, VX: 100, SpawnTime: game.gameNow()}
+	game.updateGameLogic(0.1, time.Now())
+	if proj := game.Projectiles["prj-1"]; proj == nil || math.Abs(proj.X-510) > 1e-9 {
+		t.Fatalf("снаряд за 0.1 с при скорости 100 должен сместиться на 10: %+v", proj)
+	}
+}
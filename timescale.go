@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// --- Масштаб времени ---
+
+// Симуляция идёт по игровым часам game.Clock, которые каждый тик продвигаются на шаг,
+// умноженный на game.TimeScale. Перезарядка, разминка, неуязвимость, возрождение и перерыв
+// между раундами отсчитываются по игровым часам, поэтому в замедлении они тоже замедляются.
+// Рассылка состояния, таблица очков и сетевые таймауты по-прежнему идут по настоящему времени.
+
+// Допустимый диапазон масштаба: слишком медленная игра выглядит зависшей, а слишком быстрая
+// делает шаг симуляции настолько длинным, что снаряды начинают пролетать сквозь танки
+const (
+	MinTimeScale = 0.05
+	MaxTimeScale = 4.0
+)
+
+// gameNow возвращает текущее игровое время. Вызывается под game.mutex (хотя бы на чтение).
+func (game *GameState) gameNow() time.Time {
+	return game.Clock
+}
+
+// advanceClock масштабирует шаг симуляции и продвигает на него игровые часы. Вызывается под game.mutex.
+func (game *GameState) advanceClock(dt float64) float64 {
+	dt *= game.TimeScale
+	game.Clock = game.Clock.Add(time.Duration(dt * float64(time.Second)))
+	return dt
+}
+
+// validTimeScale проверяет масштаб времени
+func validTimeScale(scale float64) error {
+	if !(scale >= MinTimeScale && scale <= MaxTimeScale) {
+		return fmt.Errorf("масштаб времени должен быть от %v до %v, получено %v", MinTimeScale, MaxTimeScale, scale)
+	}
+	return nil
+}
+
+// handleTimeScale - админский обработчик: GET возвращает текущий масштаб времени,
+// POST /debug/timescale?value=0.25 меняет его на лету. Комната выбирается параметром room
+// (по умолчанию - основная).
+func handleTimeScale(w http.ResponseWriter, r *http.Request) {
+	room := roomFromRequest(w, r)
+	if room == nil {
+		return
+	}
+	game := room.Game
+	switch r.Method {
+	case http.MethodGet:
+		game.mutex.RLock()
+		scale := game.TimeScale
+		game.mutex.RUnlock()
+		fmt.Fprintln(w, scale)
+	case http.MethodPost:
+		scale, err := strconv.ParseFloat(r.URL.Query().Get("value"), 64)
+		if err == nil {
+			err = validTimeScale(scale)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		game.mutex.Lock()
+		game.TimeScale = scale
+		game.mutex.Unlock()
+		slog.Info("Масштаб времени изменён", "room", room.ID, "scale", scale)
+		fmt.Fprintln(w, scale)
+	default:
+		http.Error(w, "метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
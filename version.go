@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// --- Версия сборки ---
+
+// Заполняются при сборке:
+//
+//	go build -ldflags "-X main.Version=1.2.0 -X main.Commit=$(git rev-parse --short HEAD) -X main.BuildTime=$(date -u +%FT%TZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// ProtocolVersion - версия протокола сообщений WebSocket. Увеличивается при несовместимых изменениях.
+const ProtocolVersion = 1
+
+// VersionInfo - ответ GET /version
+type VersionInfo struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	BuildTime       string `json:"buildTime"`
+	ProtocolVersion int    `json:"protocolVersion"`
+}
+
+// handleVersion отдаёт версию сборки и протокола
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(VersionInfo{
+		Version:         Version,
+		Commit:          Commit,
+		BuildTime:       BuildTime,
+		ProtocolVersion: ProtocolVersion,
+	})
+}
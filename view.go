@@ -0,0 +1,41 @@
+package main
+
+import "math"
+
+// --- Область видимости ---
+
+// При -view-radius > 0 каждому клиенту в состоянии игры приходят только танки и снаряды не
+// дальше этого расстояния от его танка (свой танк приходит всегда). Погибший видит то же, что
+// и тот, за кем наблюдает. Так клиент на большой карте не получает лишнего, а подсмотреть
+// положение далёких противников в сообщениях нельзя. По умолчанию (0) видно всё.
+
+// observer возвращает танк, глазами которого смотрит игрок. Вызывается под game.mutex (хотя бы на чтение).
+func (game *GameState) observer(player *Player) *Player {
+	if player.Dead {
+		if target, ok := game.Players[player.SpectatingID]; ok {
+			return target
+		}
+	}
+	return player
+}
+
+// cullView оставляет из players и projectiles видимые игроку. Вызывается под game.mutex (хотя бы на чтение).
+func (game *GameState) cullView(player *Player, players []*Player, projectiles []*Projectile) ([]*Player, []*Projectile) {
+	if config.ViewRadius <= 0 {
+		return players, projectiles
+	}
+	eye := game.observer(player)
+	visiblePlayers := make([]*Player, 0, len(players))
+	for _, p := range players {
+		if p == player || math.Hypot(p.X-eye.X, p.Y-eye.Y) <= config.ViewRadius {
+			visiblePlayers = append(visiblePlayers, p)
+		}
+	}
+	visibleProjectiles := make([]*Projectile, 0, len(projectiles))
+	for _, p := range projectiles {
+		if math.Hypot(p.X-eye.X, p.Y-eye.Y) <= config.ViewRadius {
+			visibleProjectiles = append(visibleProjectiles, p)
+		}
+	}
+	return visiblePlayers, visibleProjectiles
+}
@@ -0,0 +1,53 @@
+package main
+
+import "time"
+
+// --- Оружие ---
+
+// WeaponDef описывает оружие танка
+type WeaponDef struct {
+	Name      string  `json:"name"`
+	FireRate  float64 `json:"fireRate"`  // Выстрелов в секунду (допускаются дробные значения, например 0.5)
+	MaxActive int     `json:"maxActive"` // Сколько снарядов этого оружия у игрока может быть в полёте (0 - без ограничения)
+
+	ExplosionRadius float64 `json:"explosionRadius"` // Радиус взрыва снаряда (0 - обычный снаряд)
+
+	// Размеры снаряда: по CollisionRadius считаются попадания, VisualRadius только рисуется клиентом.
+	// Ноль - ProjectileRadius.
+	CollisionRadius float64 `json:"collisionRadius,omitempty"`
+	VisualRadius    float64 `json:"visualRadius,omitempty"`
+}
+
+// projectileRadii возвращает радиусы попадания и отрисовки снарядов оружия
+func (w WeaponDef) projectileRadii() (collision, visual float64) {
+	collision, visual = w.CollisionRadius, w.VisualRadius
+	if collision <= 0 {
+		collision = ProjectileRadius
+	}
+	if visual <= 0 {
+		visual = ProjectileRadius
+	}
+	return collision, visual
+}
+
+// Cooldown переводит скорострельность в задержку между выстрелами
+func (w WeaponDef) Cooldown() time.Duration {
+	if w.FireRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / w.FireRate)
+}
+
+const DefaultWeapon = "cannon" // Оружие нового игрока
+
+// weapons - доступное оружие
+var weapons = map[string]WeaponDef{
+	"cannon": {Name: "cannon"}, // Скорострельность - из config (см. applyConfig)
+	"rocket": {Name: "rocket", FireRate: 0.5, MaxActive: 2, ExplosionRadius: 60},
+}
+
+// applyWeapon выдаёт игроку оружие и публикует его перезарядку для клиента
+func applyWeapon(player *Player, weapon WeaponDef) {
+	player.Weapon = weapon.Name
+	player.CooldownMs = weapon.Cooldown().Milliseconds()
+}